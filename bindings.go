@@ -1,8 +1,18 @@
 package gotox
 
-//#include <tox/tox.h>
-//#include <stdlib.h>
+/*
+#include <tox/tox.h>
+#include <stdlib.h>
+
+// The instance handle is an integer, so it is converted to the userdata pointer
+// on the C side.
+static void iterate_with_handle(Tox *tox, uintptr_t handle) {
+  tox_iterate(tox, (void *)handle);
+}
+*/
 import "C"
+import "context"
+import "runtime"
 import "time"
 import "unsafe"
 
@@ -28,114 +38,112 @@ func VersionIsCompatible(major uint32, minor uint32, patch uint32) bool {
 }
 
 /* New creates and initialises a new Tox instance and returns the corresponding
- * gotox instance. */
+ * gotox instance. The options are checked with Validate first. If options is
+ * nil, the DefaultOptions are used. If options.Storage is set, the savedata is
+ * loaded from it. */
 func New(options *Options) (*Tox, error) {
 	var cTox *C.Tox
 	var toxErrNew C.TOX_ERR_NEW
 	var toxErrOptionsNew C.TOX_ERR_OPTIONS_NEW
+	var sink *logSink
+	var logHandle uintptr
+
+	if options != nil {
+		if err := options.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if options != nil && options.Storage != nil {
+		return newFromStorage(options, New)
+	}
 
 	var cOptions *C.struct_Tox_Options = C.tox_options_new(&toxErrOptionsNew)
 	if cOptions == nil || ToxErrOptionsNew(toxErrOptionsNew) != TOX_ERR_OPTIONS_NEW_OK {
-		return nil, ErrFuncFail
+		return nil, newToxError("New", ToxErrOptionsNew(toxErrOptionsNew))
 	}
 
-	if options == nil {
-		cOptions = nil
-	} else {
+	if options != nil {
 		// map options from Options to C.Tox_Options
-		cOptions.ipv6_enabled = C.bool(options.IPv6Enabled)
-		cOptions.udp_enabled = C.bool(options.UDPEnabled)
-
-		var cProxyType C.TOX_PROXY_TYPE = C.TOX_PROXY_TYPE_NONE
-		if options.ProxyType == TOX_PROXY_TYPE_HTTP {
-			cProxyType = C.TOX_PROXY_TYPE_HTTP
-		} else if options.ProxyType == TOX_PROXY_TYPE_SOCKS5 {
-			cProxyType = C.TOX_PROXY_TYPE_SOCKS5
+		free, err := options.apply(cOptions)
+		defer free()
+		if err != nil {
+			C.tox_options_free(cOptions)
+			return nil, err
 		}
-		cOptions.proxy_type = cProxyType
 
-		// max ProxyHost length is 255
-		if len(options.ProxyHost) > 255 {
-			return nil, ErrArgs
-		}
-		cProxyHost := C.CString(options.ProxyHost)
-		cOptions.proxy_host = cProxyHost
-		defer C.free(unsafe.Pointer(cProxyHost))
-
-		cOptions.proxy_port = C.uint16_t(options.ProxyPort)
-		cOptions.start_port = C.uint16_t(options.StartPort)
-		cOptions.end_port = C.uint16_t(options.EndPort)
-		cOptions.tcp_port = C.uint16_t(options.TcpPort)
-
-		if options.SaveDataType == TOX_SAVEDATA_TYPE_TOX_SAVE {
-			cOptions.savedata_type = C.TOX_SAVEDATA_TYPE_TOX_SAVE
-		} else if options.SaveDataType == TOX_SAVEDATA_TYPE_SECRET_KEY {
-			cOptions.savedata_type = C.TOX_SAVEDATA_TYPE_SECRET_KEY
+		if options.Logger != nil {
+			sink = &logSink{logger: options.Logger, minLevel: options.LogLevel}
+			logHandle = registerLogSink(sink)
+			setLogHook(cOptions, logHandle)
 		}
-
-		if len(options.SaveData) > 0 {
-			cOptions.savedata_data = (*C.uint8_t)(&options.SaveData[0])
-		} else {
-			cOptions.savedata_data = nil
-		}
-
-		cOptions.savedata_length = C.size_t(len(options.SaveData))
 	}
 
 	cTox = C.tox_new(cOptions, &toxErrNew)
 	if cTox == nil || ToxErrNew(toxErrNew) != TOX_ERR_NEW_OK {
 		C.tox_options_free(cOptions)
-		switch ToxErrNew(toxErrNew) {
-		case TOX_ERR_NEW_NULL:
-			return nil, ErrArgs
-		case TOX_ERR_NEW_MALLOC:
-			return nil, ErrNewMalloc
-		case TOX_ERR_NEW_PORT_ALLOC:
-			return nil, ErrNewPortAlloc
-		case TOX_ERR_NEW_PROXY_BAD_TYPE:
-			return nil, ErrNewProxy
-		case TOX_ERR_NEW_PROXY_BAD_HOST:
-			return nil, ErrNewProxy
-		case TOX_ERR_NEW_PROXY_BAD_PORT:
-			return nil, ErrNewProxy
-		case TOX_ERR_NEW_PROXY_NOT_FOUND:
-			return nil, ErrNewProxy
-		case TOX_ERR_NEW_LOAD_ENCRYPTED:
-			return nil, ErrNewLoadEnc
-		case TOX_ERR_NEW_LOAD_BAD_FORMAT:
-			return nil, ErrNewLoadBadFormat
-		}
-
-		if cTox == nil {
-			return nil, ErrToxNew
+		unregisterLogSink(logHandle)
+		runtime.KeepAlive(sink)
+		if ToxErrNew(toxErrNew) != TOX_ERR_NEW_OK {
+			return nil, newToxError("New", ToxErrNew(toxErrNew))
 		}
 
-		return nil, ErrUnknown
+		return nil, ErrToxNew
 	}
 
-	t := &Tox{tox: cTox, cOptions: cOptions}
+	t := &Tox{toxInstance: &toxInstance{tox: cTox, cOptions: cOptions, logSink: sink, doWake: make(chan struct{}, 1)}}
+	t.callbackTox = &Tox{toxInstance: t.toxInstance, inCallback: true}
+	t.lockedTox = &Tox{toxInstance: t.toxInstance, lockHeld: true}
+	t.handle = registerInstance(t.toxInstance)
+	t.resources = &instanceResources{tox: cTox, cOptions: cOptions, handle: t.handle, logHandle: logHandle}
+	t.cleanup = runtime.AddCleanup(t.toxInstance, releaseLeaked, t.resources)
 	return t, nil
 }
 
 /* Kill releases all resources associated with the Tox instance and disconnects
- * from the network.
- * After calling this function `t *TOX` becomes invalid. Do not use it again! */
+ * from the network. It waits for a running Iterate to return first. When called
+ * through the *Tox passed to a callback, the instance is killed as soon as
+ * Iterate returns.
+ * After calling this function every method returns ErrKilled. Calling Kill
+ * again is a no-op. */
 func (t *Tox) Kill() error {
-	if t.tox == nil {
-		return ErrToxInit
+	if err := t.lock(); err != nil {
+		if err == ErrKilled {
+			return nil
+		}
+		return err
 	}
+	defer t.unlock()
 
-	C.tox_options_free(t.cOptions)
-	C.tox_kill(t.tox)
+	if t.inCallback {
+		t.killPending = true
+		return nil
+	}
 
+	t.kill()
 	return nil
 }
 
+/* kill frees the C instance. The Tox lock must be held. */
+func (t *Tox) kill() {
+	t.cleanup.Stop()
+	t.resources.release()
+
+	t.tox = nil
+	t.cOptions = nil
+	t.killed = true
+
+	if t.events != nil {
+		t.events.close()
+	}
+}
+
 /* GetSaveDataSize returns the size of the savedata returned by GetSavedata. */
 func (t *Tox) GetSaveDataSize() (uint32, error) {
-	if t.tox == nil {
-		return 0, ErrToxInit
+	if err := t.lock(); err != nil {
+		return 0, err
 	}
+	defer t.unlock()
 
 	return uint32(C.tox_get_savedata_size(t.tox)), nil
 }
@@ -143,11 +151,12 @@ func (t *Tox) GetSaveDataSize() (uint32, error) {
 /* GetSavedata returns a byte slice of all information associated with the tox
  * instance. */
 func (t *Tox) GetSavedata() ([]byte, error) {
-	if t.tox == nil {
-		return nil, ErrToxInit
+	if err := t.lock(); err != nil {
+		return nil, err
 	}
-	size, err := t.GetSaveDataSize()
-	if err != nil || size == 0 {
+	defer t.unlock()
+	size := C.tox_get_savedata_size(t.tox)
+	if size == 0 {
 		return nil, ErrFuncFail
 	}
 
@@ -162,14 +171,11 @@ func (t *Tox) GetSavedata() ([]byte, error) {
 
 /* Bootstrap sends a "get nodes" request to the given bootstrap node with IP,
  * port, and public key to setup connections. */
-func (t *Tox) Bootstrap(address string, port uint16, publickey []byte) error {
-	if t.tox == nil {
-		return ErrToxInit
-	}
-
-	if len(publickey) != TOX_PUBLIC_KEY_SIZE {
-		return ErrArgs
+func (t *Tox) Bootstrap(address string, port uint16, publickey PublicKey) error {
+	if err := t.lock(); err != nil {
+		return err
 	}
+	defer t.unlock()
 
 	caddr := C.CString(address)
 	defer C.free(unsafe.Pointer(caddr))
@@ -177,34 +183,24 @@ func (t *Tox) Bootstrap(address string, port uint16, publickey []byte) error {
 	var toxErrBootstrap C.TOX_ERR_BOOTSTRAP
 	success := C.tox_bootstrap(t.tox, caddr, (C.uint16_t)(port), (*C.uint8_t)(&publickey[0]), &toxErrBootstrap)
 
-	switch ToxErrBootstrap(toxErrBootstrap) {
-	case TOX_ERR_BOOTSTRAP_OK:
-		return nil
-	case TOX_ERR_BOOTSTRAP_NULL:
-		return ErrArgs
-	case TOX_ERR_BOOTSTRAP_BAD_HOST:
-		return ErrFuncFail
-	case TOX_ERR_BOOTSTRAP_BAD_PORT:
-		return ErrFuncFail
+	if ToxErrBootstrap(toxErrBootstrap) != TOX_ERR_BOOTSTRAP_OK {
+		return newToxError("Bootstrap", ToxErrBootstrap(toxErrBootstrap))
 	}
 
 	if !bool(success) {
 		return ErrFuncFail
 	}
 
-	return ErrUnknown
+	return nil
 }
 
 /* AddTCPRelay adds the given node with IP, port, and public key without using
  * it as a boostrap node. */
-func (t *Tox) AddTCPRelay(address string, port uint16, publickey []byte) error {
-	if t.tox == nil {
-		return ErrToxInit
-	}
-
-	if len(publickey) != TOX_PUBLIC_KEY_SIZE {
-		return ErrArgs
+func (t *Tox) AddTCPRelay(address string, port uint16, publickey PublicKey) error {
+	if err := t.lock(); err != nil {
+		return err
 	}
+	defer t.unlock()
 
 	caddr := C.CString(address)
 	defer C.free(unsafe.Pointer(caddr))
@@ -212,29 +208,23 @@ func (t *Tox) AddTCPRelay(address string, port uint16, publickey []byte) error {
 	var toxErrBootstrap C.TOX_ERR_BOOTSTRAP
 	success := C.tox_add_tcp_relay(t.tox, caddr, (C.uint16_t)(port), (*C.uint8_t)(&publickey[0]), &toxErrBootstrap)
 
-	switch ToxErrBootstrap(toxErrBootstrap) {
-	case TOX_ERR_BOOTSTRAP_OK:
-		return nil
-	case TOX_ERR_BOOTSTRAP_NULL:
-		return ErrArgs
-	case TOX_ERR_BOOTSTRAP_BAD_HOST:
-		return ErrFuncFail
-	case TOX_ERR_BOOTSTRAP_BAD_PORT:
-		return ErrFuncFail
+	if ToxErrBootstrap(toxErrBootstrap) != TOX_ERR_BOOTSTRAP_OK {
+		return newToxError("AddTCPRelay", ToxErrBootstrap(toxErrBootstrap))
 	}
 
 	if !bool(success) {
 		return ErrFuncFail
 	}
 
-	return ErrUnknown
+	return nil
 }
 
 /* SelfGetConnectionStatus returns true if Tox is connected to the DHT. */
 func (t *Tox) SelfGetConnectionStatus() (ToxConnection, error) {
-	if t.tox == nil {
-		return TOX_CONNECTION_NONE, ErrToxInit
+	if err := t.lock(); err != nil {
+		return TOX_CONNECTION_NONE, err
 	}
+	defer t.unlock()
 
 	return ToxConnection(C.tox_self_get_connection_status(t.tox)), nil
 }
@@ -242,9 +232,10 @@ func (t *Tox) SelfGetConnectionStatus() (ToxConnection, error) {
 /* IterationInterval returns the time in milliseconds before Iterate() should be
  * called again. */
 func (t *Tox) IterationInterval() (uint32, error) {
-	if t.tox == nil {
-		return 0, ErrToxInit
+	if err := t.lock(); err != nil {
+		return 0, err
 	}
+	defer t.unlock()
 
 	ret := C.tox_iteration_interval(t.tox)
 
@@ -252,26 +243,66 @@ func (t *Tox) IterationInterval() (uint32, error) {
 }
 
 /* Iterate is the main loop. It needs to be called every IterationInterval()
- * milliseconds. */
+ * milliseconds. Calling it through the *Tox passed to a callback returns
+ * ErrIterateInCallback. */
 func (t *Tox) Iterate() error {
-	if t.tox == nil {
-		return ErrToxInit
+	return t.iterate(context.Background())
+}
+
+/* iterate implements Iterate. A delivery to a full event channel gives up when
+ * ctx is cancelled. */
+func (t *Tox) iterate(ctx context.Context) error {
+	if err := t.checkIterate(); err != nil {
+		return err
+	}
+	if err := t.lock(); err != nil {
+		return err
 	}
 
-	t.mtx.Lock()
-	C.tox_iterate(t.tox, unsafe.Pointer(t))
-	t.mtx.Unlock()
+	t.dispatching.Store(true)
+	C.iterate_with_handle(t.tox, C.uintptr_t(t.handle))
+	t.dispatching.Store(false)
+	if t.hooksStale.Swap(false) {
+		t.updateHooks()
+	}
+	stream, events := t.takeEvents()
+	panicErr := t.panicErr
+	t.panicErr = nil
 
+	if t.killPending {
+		t.kill()
+	}
+	t.unlock()
+
+	// Events are delivered after unlocking, so consumers of the event channel
+	// can call into Tox without deadlocking a blocked Iterate.
+	err := stream.deliver(ctx, events)
+	if panicErr != nil {
+		return panicErr
+	}
+	return err
+}
+
+/* checkIterate returns ErrIterateInCallback if t is used by a callback, which
+ * must not reenter tox_iterate. */
+func (t *Tox) checkIterate() error {
+	if t.toxInstance == nil {
+		return ErrToxInit
+	}
+	if t.inCallback || t.lockHeld {
+		return ErrIterateInCallback
+	}
 	return nil
 }
 
 /* SelfGetAddress returns the public address to give to others. */
-func (t *Tox) SelfGetAddress() ([]byte, error) {
-	if t.tox == nil {
-		return nil, ErrToxInit
+func (t *Tox) SelfGetAddress() (ToxID, error) {
+	var address ToxID
+	if err := t.lock(); err != nil {
+		return address, err
 	}
+	defer t.unlock()
 
-	address := make([]byte, TOX_ADDRESS_SIZE)
 	C.tox_self_get_address(t.tox, (*C.uint8_t)(&address[0]))
 
 	return address, nil
@@ -279,43 +310,46 @@ func (t *Tox) SelfGetAddress() ([]byte, error) {
 
 /* SelfSetNospam sets the nospam of your ID. */
 func (t *Tox) SelfSetNospam(nospam uint32) error {
-	if t.tox == nil {
-		return ErrToxInit
+	if err := t.lock(); err != nil {
+		return err
 	}
+	defer t.unlock()
 
 	C.tox_self_set_nospam(t.tox, (C.uint32_t)(nospam))
+	t.stateChanged()
 	return nil
 }
 
 /* SelfGetNospam returns the nospam of your ID. */
 func (t *Tox) SelfGetNospam() (uint32, error) {
-	if t.tox == nil {
-		return 0, ErrToxInit
+	if err := t.lock(); err != nil {
+		return 0, err
 	}
+	defer t.unlock()
 
 	n := C.tox_self_get_nospam(t.tox)
 	return uint32(n), nil
 }
 
 /* SelfGetPublicKey returns the publickey of your profile. */
-func (t *Tox) SelfGetPublicKey() ([]byte, error) {
-	if t.tox == nil {
-		return nil, ErrToxInit
+func (t *Tox) SelfGetPublicKey() (PublicKey, error) {
+	var publickey PublicKey
+	if err := t.lock(); err != nil {
+		return publickey, err
 	}
-
-	publickey := make([]byte, TOX_PUBLIC_KEY_SIZE)
+	defer t.unlock()
 
 	C.tox_self_get_public_key(t.tox, (*C.uint8_t)(&publickey[0]))
 	return publickey, nil
 }
 
 /* SelfGetSecretKey returns the secretkey of your profile. */
-func (t *Tox) SelfGetSecretKey() ([]byte, error) {
-	if t.tox == nil {
-		return nil, ErrToxInit
+func (t *Tox) SelfGetSecretKey() (SecretKey, error) {
+	var secretkey SecretKey
+	if err := t.lock(); err != nil {
+		return secretkey, err
 	}
-
-	secretkey := make([]byte, TOX_SECRET_KEY_SIZE)
+	defer t.unlock()
 
 	C.tox_self_get_secret_key(t.tox, (*C.uint8_t)(&secretkey[0]))
 	return secretkey, nil
@@ -323,9 +357,10 @@ func (t *Tox) SelfGetSecretKey() ([]byte, error) {
 
 /* SelfSetName sets your nickname. The maximum name length is MAX_NAME_LENGTH. */
 func (t *Tox) SelfSetName(name string) error {
-	if t.tox == nil {
-		return ErrToxInit
+	if err := t.lock(); err != nil {
+		return err
 	}
+	defer t.unlock()
 
 	var cName (*C.uint8_t)
 
@@ -337,18 +372,24 @@ func (t *Tox) SelfSetName(name string) error {
 
 	var setInfoError C.TOX_ERR_SET_INFO = C.TOX_ERR_SET_INFO_OK
 	success := C.tox_self_set_name(t.tox, cName, (C.size_t)(len(name)), &setInfoError)
-	if !bool(success) || ToxErrSetInfo(setInfoError) != TOX_ERR_SET_INFO_OK {
+	if ToxErrSetInfo(setInfoError) != TOX_ERR_SET_INFO_OK {
+		return newToxError("SelfSetName", ToxErrSetInfo(setInfoError))
+	}
+
+	if !bool(success) {
 		return ErrFuncFail
 	}
 
+	t.stateChanged()
 	return nil
 }
 
 /* SelfGetNameSize returns the length of your name. */
 func (t *Tox) SelfGetNameSize() (int64, error) {
-	if t.tox == nil {
-		return 0, ErrToxInit
+	if err := t.lock(); err != nil {
+		return 0, err
 	}
+	defer t.unlock()
 
 	ret := C.tox_self_get_name_size(t.tox)
 
@@ -357,14 +398,12 @@ func (t *Tox) SelfGetNameSize() (int64, error) {
 
 /* SelfGetName returns your nickname. */
 func (t *Tox) SelfGetName() (string, error) {
-	if t.tox == nil {
-		return "", ErrToxInit
+	if err := t.lock(); err != nil {
+		return "", err
 	}
+	defer t.unlock()
 
-	length, err := t.SelfGetNameSize()
-	if err != nil {
-		return "", ErrFuncFail
-	}
+	length := C.tox_self_get_name_size(t.tox)
 
 	name := make([]byte, length)
 
@@ -378,9 +417,10 @@ func (t *Tox) SelfGetName() (string, error) {
 /* SelfSetStatusMessage sets your status message.
  * The maximum status length is MAX_STATUS_MESSAGE_LENGTH. */
 func (t *Tox) SelfSetStatusMessage(status string) error {
-	if t.tox == nil {
-		return ErrToxInit
+	if err := t.lock(); err != nil {
+		return err
 	}
+	defer t.unlock()
 
 	var cStatus (*C.uint8_t)
 
@@ -394,17 +434,19 @@ func (t *Tox) SelfSetStatusMessage(status string) error {
 	C.tox_self_set_status_message(t.tox, cStatus, (C.size_t)(len(status)), &setInfoError)
 
 	if ToxErrSetInfo(setInfoError) != TOX_ERR_SET_INFO_OK {
-		return ErrFuncFail
+		return newToxError("SelfSetStatusMessage", ToxErrSetInfo(setInfoError))
 	}
 
+	t.stateChanged()
 	return nil
 }
 
 /* SelfGetStatusMessageSize returns the size of your status message. */
 func (t *Tox) SelfGetStatusMessageSize() (int64, error) {
-	if t.tox == nil {
-		return 0, ErrToxInit
+	if err := t.lock(); err != nil {
+		return 0, err
 	}
+	defer t.unlock()
 
 	ret := C.tox_self_get_status_message_size(t.tox)
 
@@ -413,14 +455,12 @@ func (t *Tox) SelfGetStatusMessageSize() (int64, error) {
 
 /* SelfGetStatusMessage returns your status message. */
 func (t *Tox) SelfGetStatusMessage() (string, error) {
-	if t.tox == nil {
-		return "", ErrToxInit
+	if err := t.lock(); err != nil {
+		return "", err
 	}
+	defer t.unlock()
 
-	length, err := t.SelfGetStatusMessageSize()
-	if err != nil {
-		return "", ErrFuncFail
-	}
+	length := C.tox_self_get_status_message_size(t.tox)
 
 	statusMessage := make([]byte, length)
 
@@ -433,20 +473,23 @@ func (t *Tox) SelfGetStatusMessage() (string, error) {
 
 /* SelfSetStatus sets your userstatus. */
 func (t *Tox) SelfSetStatus(userstatus ToxUserStatus) error {
-	if t.tox == nil {
-		return ErrToxInit
+	if err := t.lock(); err != nil {
+		return err
 	}
+	defer t.unlock()
 
 	C.tox_self_set_status(t.tox, (C.TOX_USER_STATUS)(userstatus))
 
+	t.stateChanged()
 	return nil
 }
 
 /* SelfGetStatus returns your status. */
 func (t *Tox) SelfGetStatus() (ToxUserStatus, error) {
-	if t.tox == nil {
-		return TOX_USERSTATUS_NONE, ErrToxInit
+	if err := t.lock(); err != nil {
+		return TOX_USERSTATUS_NONE, err
 	}
+	defer t.unlock()
 
 	n := C.tox_self_get_status(t.tox)
 
@@ -457,12 +500,13 @@ func (t *Tox) SelfGetStatus() (ToxUserStatus, error) {
  * message.
  * Returns the friend number on success, or a ToxErrFriendAdd on failure.
  */
-func (t *Tox) FriendAdd(address []byte, message string) (uint32, error) {
-	if t.tox == nil {
-		return 0, ErrToxInit
+func (t *Tox) FriendAdd(address ToxID, message string) (uint32, error) {
+	if err := t.lock(); err != nil {
+		return 0, err
 	}
+	defer t.unlock()
 
-	if len(address) != TOX_ADDRESS_SIZE || len(message) == 0 {
+	if len(message) == 0 {
 		return 0, ErrArgs
 	}
 
@@ -472,127 +516,79 @@ func (t *Tox) FriendAdd(address []byte, message string) (uint32, error) {
 	var toxErrFriendAdd C.TOX_ERR_FRIEND_ADD
 	ret := C.tox_friend_add(t.tox, caddr, cmessage, (C.size_t)(len(message)), &toxErrFriendAdd)
 
-	switch ToxErrFriendAdd(toxErrFriendAdd) {
-	case TOX_ERR_FRIEND_ADD_OK:
-		return uint32(ret), nil
-	case TOX_ERR_FRIEND_ADD_NULL:
-		return uint32(ret), ErrArgs
-	case TOX_ERR_FRIEND_ADD_TOO_LONG:
-		return uint32(ret), ErrFriendAddTooLong
-	case TOX_ERR_FRIEND_ADD_NO_MESSAGE:
-		return uint32(ret), ErrFriendAddNoMessage
-	case TOX_ERR_FRIEND_ADD_OWN_KEY:
-		return uint32(ret), ErrFriendAddOwnKey
-	case TOX_ERR_FRIEND_ADD_ALREADY_SENT:
-		return uint32(ret), ErrFriendAddAlreadySent
-	case TOX_ERR_FRIEND_ADD_BAD_CHECKSUM:
-		return uint32(ret), ErrFriendAddBadChecksum
-	case TOX_ERR_FRIEND_ADD_SET_NEW_NOSPAM:
-		return uint32(ret), ErrFriendAddSetNewNospam
-	case TOX_ERR_FRIEND_ADD_MALLOC:
-		return uint32(ret), ErrFriendAddNoMem
-	default:
-		return uint32(ret), ErrFuncFail
-	}
-
-	return uint32(ret), ErrUnknown
+	if ToxErrFriendAdd(toxErrFriendAdd) != TOX_ERR_FRIEND_ADD_OK {
+		return uint32(ret), newToxError("FriendAdd", ToxErrFriendAdd(toxErrFriendAdd))
+	}
+
+	t.stateChanged()
+	return uint32(ret), nil
 }
 
 /* FriendAddNorequest adds a friend without sending a friend request.
  * Returns the friend number on success.
  */
-func (t *Tox) FriendAddNorequest(publickey []byte) (uint32, error) {
-	if t.tox == nil {
-		return C.UINT32_MAX, ErrToxInit
-	}
-
-	if len(publickey) != TOX_PUBLIC_KEY_SIZE {
-		return C.UINT32_MAX, ErrArgs
+func (t *Tox) FriendAddNorequest(publickey PublicKey) (uint32, error) {
+	if err := t.lock(); err != nil {
+		return C.UINT32_MAX, err
 	}
+	defer t.unlock()
 
 	var toxErrFriendAdd C.TOX_ERR_FRIEND_ADD
 	ret := C.tox_friend_add_norequest(t.tox, (*C.uint8_t)(&publickey[0]), &toxErrFriendAdd)
+
+	if ToxErrFriendAdd(toxErrFriendAdd) != TOX_ERR_FRIEND_ADD_OK {
+		return uint32(ret), newToxError("FriendAddNorequest", ToxErrFriendAdd(toxErrFriendAdd))
+	}
+
 	if ret == C.UINT32_MAX {
 		return C.UINT32_MAX, ErrFuncFail
 	}
 
-	switch ToxErrFriendAdd(toxErrFriendAdd) {
-	case TOX_ERR_FRIEND_ADD_OK:
-		return uint32(ret), nil
-	case TOX_ERR_FRIEND_ADD_NULL:
-		return uint32(ret), ErrArgs
-	case TOX_ERR_FRIEND_ADD_TOO_LONG:
-		return uint32(ret), ErrFriendAddTooLong
-	case TOX_ERR_FRIEND_ADD_NO_MESSAGE:
-		return uint32(ret), ErrFriendAddNoMessage
-	case TOX_ERR_FRIEND_ADD_OWN_KEY:
-		return uint32(ret), ErrFriendAddOwnKey
-	case TOX_ERR_FRIEND_ADD_ALREADY_SENT:
-		return uint32(ret), ErrFriendAddAlreadySent
-	case TOX_ERR_FRIEND_ADD_BAD_CHECKSUM:
-		return uint32(ret), ErrFriendAddBadChecksum
-	case TOX_ERR_FRIEND_ADD_SET_NEW_NOSPAM:
-		return uint32(ret), ErrFriendAddSetNewNospam
-	case TOX_ERR_FRIEND_ADD_MALLOC:
-		return uint32(ret), ErrFriendAddNoMem
-	default:
-		return uint32(ret), ErrFuncFail
-	}
-
-	return uint32(ret), ErrUnknown
+	t.stateChanged()
+	return uint32(ret), nil
 }
 
 /* FriendDelete removes a friend. */
 func (t *Tox) FriendDelete(friendNumber uint32) error {
-	if t.tox == nil {
-		return ErrToxInit
+	if err := t.lock(); err != nil {
+		return err
 	}
+	defer t.unlock()
 
 	var toxErrFriendDelete C.TOX_ERR_FRIEND_DELETE = C.TOX_ERR_FRIEND_DELETE_OK
 	C.tox_friend_delete(t.tox, (C.uint32_t)(friendNumber), &toxErrFriendDelete)
 
-	switch ToxErrFriendDelete(toxErrFriendDelete) {
-	case TOX_ERR_FRIEND_DELETE_OK:
-		return nil
-	case TOX_ERR_FRIEND_DELETE_FRIEND_NOT_FOUND:
-		return ErrArgs
-	default:
-		return ErrFuncFail
+	if ToxErrFriendDelete(toxErrFriendDelete) != TOX_ERR_FRIEND_DELETE_OK {
+		return newToxError("FriendDelete", ToxErrFriendDelete(toxErrFriendDelete))
 	}
 
-	return ErrUnknown
+	t.stateChanged()
+	return nil
 }
 
 /* FriendByPublicKey returns the friend number associated to a given publickey. */
-func (t *Tox) FriendByPublicKey(publickey []byte) (uint32, error) {
-	if t.tox == nil {
-		return C.UINT32_MAX, ErrToxInit
-	}
-
-	if len(publickey) != TOX_PUBLIC_KEY_SIZE {
-		return C.UINT32_MAX, ErrArgs
+func (t *Tox) FriendByPublicKey(publickey PublicKey) (uint32, error) {
+	if err := t.lock(); err != nil {
+		return C.UINT32_MAX, err
 	}
+	defer t.unlock()
 
 	var toxErrFriendByPublicKey C.TOX_ERR_FRIEND_BY_PUBLIC_KEY
 	n := C.tox_friend_by_public_key(t.tox, (*C.uint8_t)(&publickey[0]), &toxErrFriendByPublicKey)
 
-	switch ToxErrFriendByPublicKey(toxErrFriendByPublicKey) {
-	case TOX_ERR_FRIEND_BY_PUBLIC_KEY_OK:
-		return uint32(n), nil
-	case TOX_ERR_FRIEND_BY_PUBLIC_KEY_NULL:
-		return uint32(n), ErrArgs
-	default:
-		return uint32(n), ErrFuncFail
+	if ToxErrFriendByPublicKey(toxErrFriendByPublicKey) != TOX_ERR_FRIEND_BY_PUBLIC_KEY_OK {
+		return uint32(n), newToxError("FriendByPublicKey", ToxErrFriendByPublicKey(toxErrFriendByPublicKey))
 	}
 
-	return uint32(n), ErrUnknown
+	return uint32(n), nil
 }
 
 /* FriendExists returns true if a friend exists with given friendNumber. */
 func (t *Tox) FriendExists(friendNumber uint32) (bool, error) {
-	if t.tox == nil {
-		return false, ErrToxInit
+	if err := t.lock(); err != nil {
+		return false, err
 	}
+	defer t.unlock()
 
 	success := C.tox_friend_exists(t.tox, (C.uint32_t)(friendNumber))
 
@@ -601,9 +597,10 @@ func (t *Tox) FriendExists(friendNumber uint32) (bool, error) {
 
 /* SelfGetFriendlistSize returns the number of friends on the friendlist. */
 func (t *Tox) SelfGetFriendlistSize() (int64, error) {
-	if t.tox == nil {
-		return 0, ErrToxInit
+	if err := t.lock(); err != nil {
+		return 0, err
 	}
+	defer t.unlock()
 	n := C.tox_self_get_friend_list_size(t.tox)
 
 	return int64(n), nil
@@ -611,14 +608,12 @@ func (t *Tox) SelfGetFriendlistSize() (int64, error) {
 
 /* SelfGetFriendlist returns a slice of uint32 containing the friendNumbers. */
 func (t *Tox) SelfGetFriendlist() ([]uint32, error) {
-	if t.tox == nil {
-		return nil, ErrToxInit
+	if err := t.lock(); err != nil {
+		return nil, err
 	}
+	defer t.unlock()
 
-	size, err := t.SelfGetFriendlistSize()
-	if err != nil {
-		return nil, ErrFuncFail
-	}
+	size := C.tox_self_get_friend_list_size(t.tox)
 
 	friendlist := make([]uint32, size)
 
@@ -630,40 +625,48 @@ func (t *Tox) SelfGetFriendlist() ([]uint32, error) {
 }
 
 /* FriendGetPublickey returns the publickey associated to that friendNumber. */
-func (t *Tox) FriendGetPublickey(friendNumber uint32) ([]byte, error) {
-	if t.tox == nil {
-		return nil, ErrToxInit
+func (t *Tox) FriendGetPublickey(friendNumber uint32) (PublicKey, error) {
+	var publickey PublicKey
+	if err := t.lock(); err != nil {
+		return publickey, err
 	}
-	publickey := make([]byte, TOX_PUBLIC_KEY_SIZE)
+	defer t.unlock()
+
 	var toxErrFriendGetPublicKey C.TOX_ERR_FRIEND_GET_PUBLIC_KEY = C.TOX_ERR_FRIEND_GET_PUBLIC_KEY_OK
 	C.tox_friend_get_public_key(t.tox, (C.uint32_t)(friendNumber), (*C.uint8_t)(&publickey[0]), &toxErrFriendGetPublicKey)
 
-	switch ToxErrFriendGetPublicKey(toxErrFriendGetPublicKey) {
-	case TOX_ERR_FRIEND_GET_PUBLIC_KEY_OK:
-		return publickey, nil
-	case TOX_ERR_FRIEND_GET_PUBLIC_KEY_FRIEND_NOT_FOUND:
-		return nil, ErrArgs
-	default:
-		return nil, ErrFuncFail
+	if ToxErrFriendGetPublicKey(toxErrFriendGetPublicKey) != TOX_ERR_FRIEND_GET_PUBLIC_KEY_OK {
+		return publickey, newToxError("FriendGetPublickey", ToxErrFriendGetPublicKey(toxErrFriendGetPublicKey))
 	}
 
-	return nil, ErrUnknown
+	return publickey, nil
 }
 
 /* FriendGetLastOnline returns the timestamp of the last time the friend with
- * the given friendNumber was seen online. */
+ * the given friendNumber was seen online, or the zero time.Time if the friend
+ * has never been seen online. */
 func (t *Tox) FriendGetLastOnline(friendNumber uint32) (time.Time, error) {
-	if t.tox == nil {
-		return time.Time{}, ErrToxInit
+	if err := t.lock(); err != nil {
+		return time.Time{}, err
 	}
+	defer t.unlock()
 
 	var toxErrFriendGetLastOnline C.TOX_ERR_FRIEND_GET_LAST_ONLINE = C.TOX_ERR_FRIEND_GET_LAST_ONLINE_OK
 	ret := C.tox_friend_get_last_online(t.tox, (C.uint32_t)(friendNumber), &toxErrFriendGetLastOnline)
 
-	if ret == C.INT64_MAX || ToxErrFriendGetLastOnline(toxErrFriendGetLastOnline) != TOX_ERR_FRIEND_GET_LAST_ONLINE_OK {
+	if ToxErrFriendGetLastOnline(toxErrFriendGetLastOnline) != TOX_ERR_FRIEND_GET_LAST_ONLINE_OK {
+		return time.Time{}, newToxError("FriendGetLastOnline", ToxErrFriendGetLastOnline(toxErrFriendGetLastOnline))
+	}
+
+	if ret == C.INT64_MAX {
 		return time.Time{}, ErrFuncFail
 	}
 
+	// toxcore reports 0 for friends that have never been online
+	if ret == 0 {
+		return time.Time{}, nil
+	}
+
 	last := time.Unix(int64(ret), 0)
 
 	return last, nil
@@ -671,15 +674,16 @@ func (t *Tox) FriendGetLastOnline(friendNumber uint32) (time.Time, error) {
 
 /* FriendGetNameSize returns the length of the name of friendNumber. */
 func (t *Tox) FriendGetNameSize(friendNumber uint32) (int64, error) {
-	if t.tox == nil {
-		return 0, ErrToxInit
+	if err := t.lock(); err != nil {
+		return 0, err
 	}
+	defer t.unlock()
 
 	var toxErrFriendQuery C.TOX_ERR_FRIEND_QUERY = C.TOX_ERR_FRIEND_QUERY_OK
 	ret := C.tox_friend_get_name_size(t.tox, (C.uint32_t)(friendNumber), &toxErrFriendQuery)
 
 	if ToxErrFriendQuery(toxErrFriendQuery) != TOX_ERR_FRIEND_QUERY_OK {
-		return 0, ErrFuncFail
+		return 0, newToxError("FriendGetNameSize", ToxErrFriendQuery(toxErrFriendQuery))
 	}
 
 	return int64(ret), nil
@@ -687,22 +691,28 @@ func (t *Tox) FriendGetNameSize(friendNumber uint32) (int64, error) {
 
 /* FriendGetName returns the name of friendNumber. */
 func (t *Tox) FriendGetName(friendNumber uint32) (string, error) {
-	if t.tox == nil {
-		return "", ErrToxInit
+	if err := t.lock(); err != nil {
+		return "", err
 	}
+	defer t.unlock()
+
+	var toxErrFriendQuery C.TOX_ERR_FRIEND_QUERY = C.TOX_ERR_FRIEND_QUERY_OK
+	length := C.tox_friend_get_name_size(t.tox, (C.uint32_t)(friendNumber), &toxErrFriendQuery)
 
-	length, err := t.FriendGetNameSize(friendNumber)
-	if err != nil {
-		return "", ErrFuncFail
+	if ToxErrFriendQuery(toxErrFriendQuery) != TOX_ERR_FRIEND_QUERY_OK {
+		return "", newToxError("FriendGetName", ToxErrFriendQuery(toxErrFriendQuery))
 	}
 
 	name := make([]byte, length)
 
 	if length > 0 {
-		var toxErrFriendQuery C.TOX_ERR_FRIEND_QUERY = C.TOX_ERR_FRIEND_QUERY_OK
 		success := C.tox_friend_get_name(t.tox, (C.uint32_t)(friendNumber), (*C.uint8_t)(&name[0]), &toxErrFriendQuery)
 
-		if success != true || ToxErrFriendQuery(toxErrFriendQuery) != TOX_ERR_FRIEND_QUERY_OK {
+		if ToxErrFriendQuery(toxErrFriendQuery) != TOX_ERR_FRIEND_QUERY_OK {
+			return "", newToxError("FriendGetName", ToxErrFriendQuery(toxErrFriendQuery))
+		}
+
+		if success != true {
 			return "", ErrFuncFail
 		}
 	}
@@ -714,15 +724,16 @@ func (t *Tox) FriendGetName(friendNumber uint32) (string, error) {
  * the given friendNumber.
  */
 func (t *Tox) FriendGetStatusMessageSize(friendNumber uint32) (int64, error) {
-	if t.tox == nil {
-		return 0, ErrToxInit
+	if err := t.lock(); err != nil {
+		return 0, err
 	}
+	defer t.unlock()
 
 	var toxErrFriendQuery C.TOX_ERR_FRIEND_QUERY = C.TOX_ERR_FRIEND_QUERY_OK
 	ret := C.tox_friend_get_status_message_size(t.tox, (C.uint32_t)(friendNumber), &toxErrFriendQuery)
 
 	if ToxErrFriendQuery(toxErrFriendQuery) != TOX_ERR_FRIEND_QUERY_OK {
-		return 0, ErrFuncFail
+		return 0, newToxError("FriendGetStatusMessageSize", ToxErrFriendQuery(toxErrFriendQuery))
 	}
 
 	return int64(ret), nil
@@ -732,15 +743,16 @@ func (t *Tox) FriendGetStatusMessageSize(friendNumber uint32) (int64, error) {
  * friendNumber.
  */
 func (t *Tox) FriendGetStatusMessage(friendNumber uint32) (string, error) {
-	if t.tox == nil {
-		return "", ErrToxInit
+	if err := t.lock(); err != nil {
+		return "", err
 	}
+	defer t.unlock()
 
 	var toxErrFriendQuery C.TOX_ERR_FRIEND_QUERY = C.TOX_ERR_FRIEND_QUERY_OK
+	size := C.tox_friend_get_status_message_size(t.tox, (C.uint32_t)(friendNumber), &toxErrFriendQuery)
 
-	size, error := t.FriendGetStatusMessageSize(friendNumber)
-	if error != nil {
-		return "", ErrFuncFail
+	if ToxErrFriendQuery(toxErrFriendQuery) != TOX_ERR_FRIEND_QUERY_OK {
+		return "", newToxError("FriendGetStatusMessage", ToxErrFriendQuery(toxErrFriendQuery))
 	}
 
 	statusMessage := make([]byte, size)
@@ -749,7 +761,11 @@ func (t *Tox) FriendGetStatusMessage(friendNumber uint32) (string, error) {
 		toxErrFriendQuery = C.TOX_ERR_FRIEND_QUERY_OK
 		n := C.tox_friend_get_status_message(t.tox, (C.uint32_t)(friendNumber), (*C.uint8_t)(&statusMessage[0]), &toxErrFriendQuery)
 
-		if n != true || ToxErrFriendQuery(toxErrFriendQuery) != TOX_ERR_FRIEND_QUERY_OK {
+		if ToxErrFriendQuery(toxErrFriendQuery) != TOX_ERR_FRIEND_QUERY_OK {
+			return "", newToxError("FriendGetStatusMessage", ToxErrFriendQuery(toxErrFriendQuery))
+		}
+
+		if n != true {
 			return "", ErrFuncFail
 		}
 	}
@@ -759,15 +775,16 @@ func (t *Tox) FriendGetStatusMessage(friendNumber uint32) (string, error) {
 
 /* FriendGetStatus returns the status of friendNumber. */
 func (t *Tox) FriendGetStatus(friendNumber uint32) (ToxUserStatus, error) {
-	if t.tox == nil {
-		return TOX_USERSTATUS_NONE, ErrToxInit
+	if err := t.lock(); err != nil {
+		return TOX_USERSTATUS_NONE, err
 	}
+	defer t.unlock()
 
 	var toxErrFriendQuery C.TOX_ERR_FRIEND_QUERY = C.TOX_ERR_FRIEND_QUERY_OK
 	status := C.tox_friend_get_status(t.tox, (C.uint32_t)(friendNumber), &toxErrFriendQuery)
 
 	if ToxErrFriendQuery(toxErrFriendQuery) != TOX_ERR_FRIEND_QUERY_OK {
-		return TOX_USERSTATUS_NONE, ErrFuncFail
+		return TOX_USERSTATUS_NONE, newToxError("FriendGetStatus", ToxErrFriendQuery(toxErrFriendQuery))
 	}
 
 	return ToxUserStatus(status), nil
@@ -775,15 +792,16 @@ func (t *Tox) FriendGetStatus(friendNumber uint32) (ToxUserStatus, error) {
 
 /* FriendGetConnectionStatus returns true if the friend is connected. */
 func (t *Tox) FriendGetConnectionStatus(friendNumber uint32) (ToxConnection, error) {
-	if t.tox == nil {
-		return TOX_CONNECTION_NONE, ErrToxInit
+	if err := t.lock(); err != nil {
+		return TOX_CONNECTION_NONE, err
 	}
+	defer t.unlock()
 
 	var toxErrFriendQuery C.TOX_ERR_FRIEND_QUERY = C.TOX_ERR_FRIEND_QUERY_OK
 	status := C.tox_friend_get_connection_status(t.tox, (C.uint32_t)(friendNumber), &toxErrFriendQuery)
 
 	if ToxErrFriendQuery(toxErrFriendQuery) != TOX_ERR_FRIEND_QUERY_OK {
-		return TOX_CONNECTION_NONE, ErrFuncFail
+		return TOX_CONNECTION_NONE, newToxError("FriendGetConnectionStatus", ToxErrFriendQuery(toxErrFriendQuery))
 	}
 
 	return ToxConnection(status), nil
@@ -791,15 +809,16 @@ func (t *Tox) FriendGetConnectionStatus(friendNumber uint32) (ToxConnection, err
 
 /* FriendGetTyping returns true if friendNumber is typing. */
 func (t *Tox) FriendGetTyping(friendNumber uint32) (bool, error) {
-	if t.tox == nil {
-		return false, ErrToxInit
+	if err := t.lock(); err != nil {
+		return false, err
 	}
+	defer t.unlock()
 
 	var toxErrFriendQuery C.TOX_ERR_FRIEND_QUERY = C.TOX_ERR_FRIEND_QUERY_OK
 	istyping := C.tox_friend_get_typing(t.tox, (C.uint32_t)(friendNumber), &toxErrFriendQuery)
 
 	if ToxErrFriendQuery(toxErrFriendQuery) != TOX_ERR_FRIEND_QUERY_OK {
-		return false, ErrFuncFail
+		return false, newToxError("FriendGetTyping", ToxErrFriendQuery(toxErrFriendQuery))
 	}
 
 	return bool(istyping), nil
@@ -807,14 +826,19 @@ func (t *Tox) FriendGetTyping(friendNumber uint32) (bool, error) {
 
 /* SelfSetTyping sets your typing status to a friend. */
 func (t *Tox) SelfSetTyping(friendNumber uint32, typing bool) error {
-	if t.tox == nil {
-		return ErrToxInit
+	if err := t.lock(); err != nil {
+		return err
 	}
+	defer t.unlock()
 
 	var toxErrSetTyping C.TOX_ERR_SET_TYPING = C.TOX_ERR_SET_TYPING_OK
 	success := C.tox_self_set_typing(t.tox, (C.uint32_t)(friendNumber), (C._Bool)(typing), &toxErrSetTyping)
 
-	if !bool(success) || ToxErrSetTyping(toxErrSetTyping) != TOX_ERR_SET_TYPING_OK {
+	if ToxErrSetTyping(toxErrSetTyping) != TOX_ERR_SET_TYPING_OK {
+		return newToxError("SelfSetTyping", ToxErrSetTyping(toxErrSetTyping))
+	}
+
+	if !bool(success) {
 		return ErrFuncFail
 	}
 
@@ -827,9 +851,10 @@ func (t *Tox) SelfSetTyping(friendNumber uint32, typing bool) error {
  * Returns the message ID if successful, an error otherwise.
  */
 func (t *Tox) FriendSendMessage(friendNumber uint32, messagetype ToxMessageType, message string) (uint32, error) {
-	if t.tox == nil {
-		return 0, ErrToxInit
+	if err := t.lock(); err != nil {
+		return 0, err
 	}
+	defer t.unlock()
 
 	if len(message) == 0 {
 		return 0, ErrArgs
@@ -848,7 +873,7 @@ func (t *Tox) FriendSendMessage(friendNumber uint32, messagetype ToxMessageType,
 	n := C.tox_friend_send_message(t.tox, (C.uint32_t)(friendNumber), cMessageType, cMessage, (C.size_t)(len(message)), &toxFriendSendMessageError)
 
 	if ToxErrFriendSendMessage(toxFriendSendMessageError) != TOX_ERR_FRIEND_SEND_MESSAGE_OK {
-		return 0, ErrFuncFail
+		return 0, newToxError("FriendSendMessage", ToxErrFriendSendMessage(toxFriendSendMessageError))
 	}
 
 	return uint32(n), nil
@@ -857,9 +882,10 @@ func (t *Tox) FriendSendMessage(friendNumber uint32, messagetype ToxMessageType,
 /* Hash generates a cryptographic hash of the given data (can be used to cache
  * avatars). */
 func (t *Tox) Hash(data []byte) ([]byte, error) {
-	if t.tox == nil {
-		return nil, ErrToxInit
+	if err := t.lock(); err != nil {
+		return nil, err
 	}
+	defer t.unlock()
 
 	var cData *C.uint8_t
 
@@ -881,9 +907,10 @@ func (t *Tox) Hash(data []byte) ([]byte, error) {
 
 /* FileControl sends a FileControl to a friend with the given friendNumber. */
 func (t *Tox) FileControl(friendNumber uint32, fileNumber uint32, fileControl ToxFileControl) error {
-	if t.tox == nil {
-		return ErrToxInit
+	if err := t.lock(); err != nil {
+		return err
 	}
+	defer t.unlock()
 
 	var cFileControl C.TOX_FILE_CONTROL
 	switch ToxFileControl(fileControl) {
@@ -898,7 +925,11 @@ func (t *Tox) FileControl(friendNumber uint32, fileNumber uint32, fileControl To
 	var toxErrFileControl C.TOX_ERR_FILE_CONTROL
 	success := C.tox_file_control(t.tox, (C.uint32_t)(friendNumber), (C.uint32_t)(fileNumber), cFileControl, &toxErrFileControl)
 
-	if !bool(success) || ToxErrFileControl(toxErrFileControl) != TOX_ERR_FILE_CONTROL_OK {
+	if ToxErrFileControl(toxErrFileControl) != TOX_ERR_FILE_CONTROL_OK {
+		return newToxError("FileControl", ToxErrFileControl(toxErrFileControl))
+	}
+
+	if !bool(success) {
 		return ErrFuncFail
 	}
 
@@ -908,14 +939,19 @@ func (t *Tox) FileControl(friendNumber uint32, fileNumber uint32, fileControl To
 /* FileSeek sends a file seek control command to a friend for a given file
  * transfer. */
 func (t *Tox) FileSeek(friendNumber uint32, fileNumber uint32, position uint64) error {
-	if t.tox == nil {
-		return ErrToxInit
+	if err := t.lock(); err != nil {
+		return err
 	}
+	defer t.unlock()
 
 	var toxErrFileSeek C.TOX_ERR_FILE_SEEK
 	success := C.tox_file_seek(t.tox, C.uint32_t(friendNumber), C.uint32_t(fileNumber), C.uint64_t(position), &toxErrFileSeek)
 
-	if !bool(success) || ToxErrFileSeek(toxErrFileSeek) != TOX_ERR_FILE_SEEK_OK {
+	if ToxErrFileSeek(toxErrFileSeek) != TOX_ERR_FILE_SEEK_OK {
+		return newToxError("FileSeek", ToxErrFileSeek(toxErrFileSeek))
+	}
+
+	if !bool(success) {
 		return ErrFuncFail
 	}
 
@@ -924,15 +960,20 @@ func (t *Tox) FileSeek(friendNumber uint32, fileNumber uint32, position uint64)
 
 /* FileGetFileId returns the file id associated to the file transfer. */
 func (t *Tox) FileGetFileId(friendNumber uint32, fileNumber uint32) ([]byte, error) {
-	if t.tox == nil {
-		return nil, ErrToxInit
+	if err := t.lock(); err != nil {
+		return nil, err
 	}
+	defer t.unlock()
 
 	fileId := make([]byte, TOX_FILE_ID_LENGTH)
 
 	var toxErrFileGet C.TOX_ERR_FILE_GET
 	success := C.tox_file_get_file_id(t.tox, C.uint32_t(friendNumber), C.uint32_t(fileNumber), (*C.uint8_t)(&fileId[0]), &toxErrFileGet)
-	if !bool(success) || ToxErrFileGet(toxErrFileGet) != TOX_ERR_FILE_GET_OK {
+	if ToxErrFileGet(toxErrFileGet) != TOX_ERR_FILE_GET_OK {
+		return nil, newToxError("FileGetFileId", ToxErrFileGet(toxErrFileGet))
+	}
+
+	if !bool(success) {
 		return nil, ErrFuncFail
 	}
 
@@ -941,9 +982,10 @@ func (t *Tox) FileGetFileId(friendNumber uint32, fileNumber uint32) ([]byte, err
 
 /* FileSend sends a file transmission request. */
 func (t *Tox) FileSend(friendNumber uint32, fileKind ToxFileKind, fileLength uint64, fileID []byte, fileName string) (uint32, error) {
-	if t.tox == nil {
-		return 0, ErrToxInit
+	if err := t.lock(); err != nil {
+		return 0, err
 	}
+	defer t.unlock()
 
 	var cFileKind = C.TOX_FILE_KIND_DATA
 	switch ToxFileKind(fileKind) {
@@ -974,7 +1016,11 @@ func (t *Tox) FileSend(friendNumber uint32, fileKind ToxFileKind, fileLength uin
 	var toxErrFileSend C.TOX_ERR_FILE_SEND
 	n := C.tox_file_send(t.tox, (C.uint32_t)(friendNumber), (C.uint32_t)(cFileKind), (C.uint64_t)(fileLength), cFileID, cFileName, (C.size_t)(len(fileName)), &toxErrFileSend)
 
-	if n == C.UINT32_MAX || ToxErrFileSend(toxErrFileSend) != TOX_ERR_FILE_SEND_OK {
+	if ToxErrFileSend(toxErrFileSend) != TOX_ERR_FILE_SEND_OK {
+		return 0, newToxError("FileSend", ToxErrFileSend(toxErrFileSend))
+	}
+
+	if n == C.UINT32_MAX {
 		return 0, ErrFuncFail
 	}
 	return uint32(n), nil
@@ -982,9 +1028,10 @@ func (t *Tox) FileSend(friendNumber uint32, fileKind ToxFileKind, fileLength uin
 
 /* FileSendChunk sends a chunk of file data to a friend. */
 func (t *Tox) FileSendChunk(friendNumber uint32, fileNumber uint32, position uint64, data []byte) error {
-	if t.tox == nil {
-		return ErrToxInit
+	if err := t.lock(); err != nil {
+		return err
 	}
+	defer t.unlock()
 
 	var cData *C.uint8_t
 
@@ -997,7 +1044,11 @@ func (t *Tox) FileSendChunk(friendNumber uint32, fileNumber uint32, position uin
 	var toxErrFileSendChunk C.TOX_ERR_FILE_SEND_CHUNK
 	success := C.tox_file_send_chunk(t.tox, (C.uint32_t)(friendNumber), (C.uint32_t)(fileNumber), (C.uint64_t)(position), cData, (C.size_t)(len(data)), &toxErrFileSendChunk)
 
-	if !bool(success) || ToxErrFileSendChunk(toxErrFileSendChunk) != TOX_ERR_FILE_SEND_CHUNK_OK {
+	if ToxErrFileSendChunk(toxErrFileSendChunk) != TOX_ERR_FILE_SEND_CHUNK_OK {
+		return newToxError("FileSendChunk", ToxErrFileSendChunk(toxErrFileSendChunk))
+	}
+
+	if !bool(success) {
 		return ErrFuncFail
 	}
 	return nil
@@ -1007,9 +1058,10 @@ func (t *Tox) FileSendChunk(friendNumber uint32, fileNumber uint32, position uin
  * The first byte of data must be in the range 200-254. Maximum length of a
  * custom packet is TOX_MAX_CUSTOM_PACKET_SIZE. */
 func (t *Tox) FriendSendLossyPacket(friendNumber uint32, data []byte) error {
-	if t.tox == nil {
-		return ErrToxInit
+	if err := t.lock(); err != nil {
+		return err
 	}
+	defer t.unlock()
 
 	var cData *C.uint8_t
 
@@ -1022,25 +1074,21 @@ func (t *Tox) FriendSendLossyPacket(friendNumber uint32, data []byte) error {
 	var toxErrFriendCustomPacket C.TOX_ERR_FRIEND_CUSTOM_PACKET
 	C.tox_friend_send_lossy_packet(t.tox, C.uint32_t(friendNumber), cData, C.size_t(len(data)), &toxErrFriendCustomPacket)
 
-	switch ToxErrFriendCustomPacket(toxErrFriendCustomPacket) {
-	case TOX_ERR_FRIEND_CUSTOM_PACKET_OK:
-		return nil
-	case TOX_ERR_FRIEND_CUSTOM_PACKET_NULL:
-		return ErrArgs
-	default:
-		return ErrFuncFail
+	if ToxErrFriendCustomPacket(toxErrFriendCustomPacket) != TOX_ERR_FRIEND_CUSTOM_PACKET_OK {
+		return newToxError("FriendSendLossyPacket", ToxErrFriendCustomPacket(toxErrFriendCustomPacket))
 	}
 
-	return ErrUnknown
+	return nil
 }
 
 /* FriendSendLosslessPacket sends a custom lossless packet to a friend.
  * The first byte of data must be in the range 160-191. Maximum length of a
  * custom packet is TOX_MAX_CUSTOM_PACKET_SIZE. */
 func (t *Tox) FriendSendLosslessPacket(friendNumber uint32, data []byte) error {
-	if t.tox == nil {
-		return ErrToxInit
+	if err := t.lock(); err != nil {
+		return err
 	}
+	defer t.unlock()
 
 	var cData *C.uint8_t
 
@@ -1053,25 +1101,20 @@ func (t *Tox) FriendSendLosslessPacket(friendNumber uint32, data []byte) error {
 	var toxErrFriendCustomPacket C.TOX_ERR_FRIEND_CUSTOM_PACKET
 	C.tox_friend_send_lossless_packet(t.tox, C.uint32_t(friendNumber), cData, C.size_t(len(data)), &toxErrFriendCustomPacket)
 
-	switch ToxErrFriendCustomPacket(toxErrFriendCustomPacket) {
-	case TOX_ERR_FRIEND_CUSTOM_PACKET_OK:
-		return nil
-	case TOX_ERR_FRIEND_CUSTOM_PACKET_NULL:
-		return ErrArgs
-	default:
-		return ErrFuncFail
+	if ToxErrFriendCustomPacket(toxErrFriendCustomPacket) != TOX_ERR_FRIEND_CUSTOM_PACKET_OK {
+		return newToxError("FriendSendLosslessPacket", ToxErrFriendCustomPacket(toxErrFriendCustomPacket))
 	}
 
-	return ErrUnknown
+	return nil
 }
 
 /* SelfGetDhtId returns the temporary DHT public key of this instance. */
-func (t *Tox) SelfGetDhtId() ([]byte, error) {
-	if t.tox == nil {
-		return nil, ErrToxInit
+func (t *Tox) SelfGetDhtId() (PublicKey, error) {
+	var publickey PublicKey
+	if err := t.lock(); err != nil {
+		return publickey, err
 	}
-
-	publickey := make([]byte, TOX_PUBLIC_KEY_SIZE)
+	defer t.unlock()
 
 	C.tox_self_get_dht_id(t.tox, (*C.uint8_t)(&publickey[0]))
 	return publickey, nil
@@ -1079,15 +1122,16 @@ func (t *Tox) SelfGetDhtId() ([]byte, error) {
 
 /* SelfGetUDPPort returns the UDP port the Tox instance is bound to. */
 func (t *Tox) SelfGetUDPPort() (uint16, error) {
-	if t.tox == nil {
-		return 0, ErrToxInit
+	if err := t.lock(); err != nil {
+		return 0, err
 	}
+	defer t.unlock()
 
 	var toxErrGetPort C.TOX_ERR_GET_PORT
 	port := C.tox_self_get_udp_port(t.tox, &toxErrGetPort)
 
 	if ToxErrGetPort(toxErrGetPort) != TOX_ERR_GET_PORT_OK {
-		return 0, ErrFuncFail
+		return 0, newToxError("SelfGetUDPPort", ToxErrGetPort(toxErrGetPort))
 	}
 
 	return uint16(port), nil
@@ -1096,15 +1140,16 @@ func (t *Tox) SelfGetUDPPort() (uint16, error) {
 /* SelfGetTCPPort returns the TCP port the Tox instance is bound to. This is
  * only relevant if the instance is acting as a TCP relay. */
 func (t *Tox) SelfGetTCPPort() (uint16, error) {
-	if t.tox == nil {
-		return 0, ErrToxInit
+	if err := t.lock(); err != nil {
+		return 0, err
 	}
+	defer t.unlock()
 
 	var toxErrGetPort C.TOX_ERR_GET_PORT
 	port := C.tox_self_get_tcp_port(t.tox, &toxErrGetPort)
 
 	if ToxErrGetPort(toxErrGetPort) != TOX_ERR_GET_PORT_OK {
-		return 0, ErrFuncFail
+		return 0, newToxError("SelfGetTCPPort", ToxErrGetPort(toxErrGetPort))
 	}
 
 	return uint16(port), nil
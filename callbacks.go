@@ -37,7 +37,7 @@ type OnFriendTypingChanges func(tox *Tox, friendnumber uint32, istyping bool)
 type OnFriendReadReceipt func(tox *Tox, friendnumber uint32, messageid uint32)
 
 /* This event is triggered when a friend request is received. */
-type OnFriendRequest func(tox *Tox, publickey []byte, message string)
+type OnFriendRequest func(tox *Tox, publickey PublicKey, message string)
 
 /* This event is triggered when a message from a friend is received. */
 type OnFriendMessage func(tox *Tox, friendnumber uint32, messagetype ToxMessageType, message string)
@@ -64,110 +64,298 @@ type OnFriendLossyPacket func(tox *Tox, friendnumber uint32, data []byte)
 type OnFriendLosslessPacket func(tox *Tox, friendnumber uint32, data []byte)
 
 /*
- * Functions to register the callbacks.
+ * Functions to register the callbacks. Passing nil unregisters the callback.
  */
 
 func (t *Tox) CallbackSelfConnectionStatusChanges(f OnSelfConnectionStatusChanges) {
-	if t.tox != nil {
-		t.onSelfConnectionStatusChanges = f
-		C.set_callback_self_connection_status(t.tox)
+	if t.lock() != nil {
+		return
 	}
+	defer t.unlock()
+
+	t.onSelfConnectionStatusChanges = f
+	t.updateHook(hookSelfConnectionStatus, f != nil)
 }
 
 func (t *Tox) CallbackFriendNameChanges(f OnFriendNameChanges) {
-	if t.tox != nil {
-		t.onFriendNameChanges = f
-		C.set_callback_friend_name(t.tox)
+	if t.lock() != nil {
+		return
 	}
+	defer t.unlock()
+
+	t.onFriendNameChanges = f
+	t.updateHook(hookFriendName, f != nil)
 }
 
 func (t *Tox) CallbackFriendStatusMessageChanges(f OnFriendStatusMessageChanges) {
-	if t.tox != nil {
-		t.onFriendStatusMessageChanges = f
-		C.set_callback_friend_status_message(t.tox)
+	if t.lock() != nil {
+		return
 	}
+	defer t.unlock()
+
+	t.onFriendStatusMessageChanges = f
+	t.updateHook(hookFriendStatusMessage, f != nil)
 }
 
 func (t *Tox) CallbackFriendStatusChanges(f OnFriendStatusChanges) {
-	if t.tox != nil {
-		t.onFriendStatusChanges = f
-		C.set_callback_friend_status(t.tox)
+	if t.lock() != nil {
+		return
 	}
+	defer t.unlock()
+
+	t.onFriendStatusChanges = f
+	t.updateHook(hookFriendStatus, f != nil)
 }
 
 func (t *Tox) CallbackFriendConnectionStatusChanges(f OnFriendConnectionStatusChanges) {
-	if t.tox != nil {
-		t.onFriendConnectionStatusChanges = f
-		C.set_callback_friend_connection_status(t.tox)
+	if t.lock() != nil {
+		return
 	}
+	defer t.unlock()
+
+	t.onFriendConnectionStatusChanges = f
+	t.updateHook(hookFriendConnectionStatus, f != nil)
 }
 
 func (t *Tox) CallbackFriendTypingChanges(f OnFriendTypingChanges) {
-	if t.tox != nil {
-		t.onFriendTypingChanges = f
-		C.set_callback_friend_typing(t.tox)
+	if t.lock() != nil {
+		return
 	}
+	defer t.unlock()
+
+	t.onFriendTypingChanges = f
+	t.updateHook(hookFriendTyping, f != nil)
 }
 
 func (t *Tox) CallbackFriendReadReceipt(f OnFriendReadReceipt) {
-	if t.tox != nil {
-		t.onFriendReadReceipt = f
-		C.set_callback_friend_read_receipt(t.tox)
+	if t.lock() != nil {
+		return
 	}
+	defer t.unlock()
+
+	t.onFriendReadReceipt = f
+	t.updateHook(hookFriendReadReceipt, f != nil)
 }
 
 func (t *Tox) CallbackFriendRequest(f OnFriendRequest) {
-	if t.tox != nil {
-		t.onFriendRequest = f
-		C.set_callback_friend_request(t.tox)
+	if t.lock() != nil {
+		return
 	}
+	defer t.unlock()
+
+	t.onFriendRequest = f
+	t.updateHook(hookFriendRequest, f != nil)
 }
 
 func (t *Tox) CallbackFriendMessage(f OnFriendMessage) {
-	if t.tox != nil {
-		t.onFriendMessage = f
-		C.set_callback_friend_message(t.tox)
+	if t.lock() != nil {
+		return
 	}
+	defer t.unlock()
+
+	t.onFriendMessage = f
+	t.updateHook(hookFriendMessage, f != nil)
 }
 
 func (t *Tox) CallbackFileRecvControl(f OnFileRecvControl) {
-	if t.tox != nil {
-		t.onFileRecvControl = f
-		C.set_callback_file_recv_control(t.tox)
+	if t.lock() != nil {
+		return
 	}
+	defer t.unlock()
+
+	t.onFileRecvControl = f
+	t.updateHook(hookFileRecvControl, f != nil)
 }
 
 func (t *Tox) CallbackFileChunkRequest(f OnFileChunkRequest) {
-	if t.tox != nil {
-		t.onFileChunkRequest = f
-		C.set_callback_file_chunk_request(t.tox)
+	if t.lock() != nil {
+		return
 	}
+	defer t.unlock()
+
+	t.onFileChunkRequest = f
+	t.updateHook(hookFileChunkRequest, f != nil)
 }
 
 func (t *Tox) CallbackFileRecv(f OnFileRecv) {
-	if t.tox != nil {
-		t.onFileRecv = f
-		C.set_callback_file_recv(t.tox)
+	if t.lock() != nil {
+		return
 	}
+	defer t.unlock()
+
+	t.onFileRecv = f
+	t.updateHook(hookFileRecv, f != nil)
 }
 
 func (t *Tox) CallbackFileRecvChunk(f OnFileRecvChunk) {
-	if t.tox != nil {
-		t.onFileRecvChunk = f
-		C.set_callback_file_recv_chunk(t.tox)
+	if t.lock() != nil {
+		return
 	}
+	defer t.unlock()
+
+	t.onFileRecvChunk = f
+	t.updateHook(hookFileRecvChunk, f != nil)
 }
 
 func (t *Tox) CallbackFriendLossyPacket(f OnFriendLossyPacket) {
-	if t.tox != nil {
-		t.onFriendLossyPacket = f
-		C.set_callback_friend_lossy_packet(t.tox)
+	if t.lock() != nil {
+		return
 	}
+	defer t.unlock()
+
+	t.onFriendLossyPacket = f
+	t.updateHook(hookFriendLossyPacket, f != nil)
 }
 
 func (t *Tox) CallbackFriendLosslessPacket(f OnFriendLosslessPacket) {
-	if t.tox != nil {
-		t.onFriendLosslessPacket = f
+	if t.lock() != nil {
+		return
+	}
+	defer t.unlock()
+
+	t.onFriendLosslessPacket = f
+	t.updateHook(hookFriendLosslessPacket, f != nil)
+}
+
+/* installHook registers the C hook for kind with toxcore. The Tox lock must be
+ * held. */
+func (t *Tox) installHook(kind hookKind) {
+	switch kind {
+	case hookSelfConnectionStatus:
+		C.set_callback_self_connection_status(t.tox)
+	case hookFriendName:
+		C.set_callback_friend_name(t.tox)
+	case hookFriendStatusMessage:
+		C.set_callback_friend_status_message(t.tox)
+	case hookFriendStatus:
+		C.set_callback_friend_status(t.tox)
+	case hookFriendConnectionStatus:
+		C.set_callback_friend_connection_status(t.tox)
+	case hookFriendTyping:
+		C.set_callback_friend_typing(t.tox)
+	case hookFriendReadReceipt:
+		C.set_callback_friend_read_receipt(t.tox)
+	case hookFriendRequest:
+		C.set_callback_friend_request(t.tox)
+	case hookFriendMessage:
+		C.set_callback_friend_message(t.tox)
+	case hookFileRecvControl:
+		C.set_callback_file_recv_control(t.tox)
+	case hookFileChunkRequest:
+		C.set_callback_file_chunk_request(t.tox)
+	case hookFileRecv:
+		C.set_callback_file_recv(t.tox)
+	case hookFileRecvChunk:
+		C.set_callback_file_recv_chunk(t.tox)
+	case hookFriendLossyPacket:
+		C.set_callback_friend_lossy_packet(t.tox)
+	case hookFriendLosslessPacket:
 		C.set_callback_friend_lossless_packet(t.tox)
 	}
 }
+
+/* uninstallHook unregisters the C hook for kind from toxcore. The Tox lock
+ * must be held. */
+func (t *Tox) uninstallHook(kind hookKind) {
+	switch kind {
+	case hookSelfConnectionStatus:
+		C.unset_callback_self_connection_status(t.tox)
+	case hookFriendName:
+		C.unset_callback_friend_name(t.tox)
+	case hookFriendStatusMessage:
+		C.unset_callback_friend_status_message(t.tox)
+	case hookFriendStatus:
+		C.unset_callback_friend_status(t.tox)
+	case hookFriendConnectionStatus:
+		C.unset_callback_friend_connection_status(t.tox)
+	case hookFriendTyping:
+		C.unset_callback_friend_typing(t.tox)
+	case hookFriendReadReceipt:
+		C.unset_callback_friend_read_receipt(t.tox)
+	case hookFriendRequest:
+		C.unset_callback_friend_request(t.tox)
+	case hookFriendMessage:
+		C.unset_callback_friend_message(t.tox)
+	case hookFileRecvControl:
+		C.unset_callback_file_recv_control(t.tox)
+	case hookFileChunkRequest:
+		C.unset_callback_file_chunk_request(t.tox)
+	case hookFileRecv:
+		C.unset_callback_file_recv(t.tox)
+	case hookFileRecvChunk:
+		C.unset_callback_file_recv_chunk(t.tox)
+	case hookFriendLossyPacket:
+		C.unset_callback_friend_lossy_packet(t.tox)
+	case hookFriendLosslessPacket:
+		C.unset_callback_friend_lossless_packet(t.tox)
+	}
+}
+
+/* updateHook installs the C hook for kind if anything is interested in it, and
+ * unregisters it otherwise. The Tox lock must be held. */
+func (t *Tox) updateHook(kind hookKind, hasCallback bool) {
+	if hasCallback || t.events != nil || len(t.subscribers.get(kind)) > 0 {
+		t.installHook(kind)
+	} else {
+		t.uninstallHook(kind)
+	}
+}
+
+/* updateHooks updates the C hooks of all kinds. The Tox lock must be held. */
+func (t *Tox) updateHooks() {
+	for kind := hookKind(0); kind < hookCount; kind++ {
+		t.updateHook(kind, t.hasCallback(kind))
+	}
+}
+
+/* hasCallback returns true if a Callback* handler is registered for kind. The
+ * Tox lock must be held. */
+func (t *Tox) hasCallback(kind hookKind) bool {
+	switch kind {
+	case hookSelfConnectionStatus:
+		return t.onSelfConnectionStatusChanges != nil
+	case hookFriendName:
+		return t.onFriendNameChanges != nil
+	case hookFriendStatusMessage:
+		return t.onFriendStatusMessageChanges != nil
+	case hookFriendStatus:
+		return t.onFriendStatusChanges != nil
+	case hookFriendConnectionStatus:
+		return t.onFriendConnectionStatusChanges != nil
+	case hookFriendTyping:
+		return t.onFriendTypingChanges != nil
+	case hookFriendReadReceipt:
+		return t.onFriendReadReceipt != nil
+	case hookFriendRequest:
+		return t.onFriendRequest != nil
+	case hookFriendMessage:
+		return t.onFriendMessage != nil
+	case hookFileRecvControl:
+		return t.onFileRecvControl != nil
+	case hookFileChunkRequest:
+		return t.onFileChunkRequest != nil
+	case hookFileRecv:
+		return t.onFileRecv != nil
+	case hookFileRecvChunk:
+		return t.onFileRecvChunk != nil
+	case hookFriendLossyPacket:
+		return t.onFriendLossyPacket != nil
+	case hookFriendLosslessPacket:
+		return t.onFriendLosslessPacket != nil
+	}
+	return false
+}
+
+/* registerAllHooks installs every C hook so that all events reach the event
+ * channel, even if no callback has been registered. */
+func (t *Tox) registerAllHooks() {
+	for kind := hookKind(0); kind < hookCount; kind++ {
+		t.installHook(kind)
+	}
+}
+
+/* setLogHook makes toxcore pass its log messages to the log sink with the given
+ * handle. */
+func setLogHook(cOptions *C.struct_Tox_Options, handle uintptr) {
+	C.set_log_callback(cOptions, C.uintptr_t(handle))
+}
@@ -1,6 +1,7 @@
 package gotox
 
 //#include <tox/tox.h>
+//#include <tox/toxencryptsave.h>
 import "C"
 import "errors"
 
@@ -79,14 +80,65 @@ const (
 	TOX_FILE_CONTROL_CANCEL ToxFileControl = C.TOX_FILE_CONTROL_CANCEL
 )
 
+type ToxLogLevel C.enum_TOX_LOG_LEVEL
+
+const (
+	TOX_LOG_LEVEL_TRACE   ToxLogLevel = C.TOX_LOG_LEVEL_TRACE
+	TOX_LOG_LEVEL_DEBUG   ToxLogLevel = C.TOX_LOG_LEVEL_DEBUG
+	TOX_LOG_LEVEL_INFO    ToxLogLevel = C.TOX_LOG_LEVEL_INFO
+	TOX_LOG_LEVEL_WARNING ToxLogLevel = C.TOX_LOG_LEVEL_WARNING
+	TOX_LOG_LEVEL_ERROR   ToxLogLevel = C.TOX_LOG_LEVEL_ERROR
+)
+
 /* === Errors === */
 // General errors
 var (
 	ErrToxNew   = errors.New("Error initializing Tox")
 	ErrToxInit  = errors.New("Tox not initialized")
+	ErrKilled   = errors.New("Tox instance has been killed")
 	ErrArgs     = errors.New("Nil arguments or wrong size")
 	ErrFuncFail = errors.New("Function failed")
 	ErrUnknown  = errors.New("An unknown error occoured")
+
+	ErrNotInCallback     = errors.New("Tox passed to a callback used outside of the callback")
+	ErrIterateInCallback = errors.New("Iterate called from a callback")
+)
+
+// Friend errors
+var (
+	ErrFriendGone              = errors.New("Friend has been deleted")
+	ErrFriendRequestNotPending = errors.New("No pending friend request for this key")
+)
+
+// ToxID and key errors
+var (
+	ErrKeyInvalid       = errors.New("Invalid key")
+	ErrToxIDInvalid     = errors.New("Invalid Tox ID")
+	ErrToxIDBadChecksum = errors.New("Bad checksum in Tox ID")
+)
+
+// toxencryptsave errors
+var (
+	ErrPassKeyDestroyed = errors.New("Pass key has been destroyed")
+)
+
+// Storage errors
+var (
+	ErrStorageLocked = errors.New("Profile is in use by another instance")
+)
+
+// Options errors
+var (
+	ErrOptionUnsupported      = errors.New("Option not supported by the linked toxcore version")
+	ErrOptionInvalidValue     = errors.New("Invalid value")
+	ErrOptionPortRange        = errors.New("Start port is greater than end port")
+	ErrOptionProxyHostMissing = errors.New("Proxy host missing")
+	ErrOptionProxyHostTooLong = errors.New("Proxy host too long")
+	ErrOptionProxyPortMissing = errors.New("Proxy port missing")
+	ErrOptionSaveDataUnused   = errors.New("Savedata given but savedata type is none")
+	ErrOptionSaveDataMissing  = errors.New("Savedata type given but savedata is empty")
+	ErrOptionSecretKeySize    = errors.New("Secret key has the wrong size")
+	ErrOptionStorageSaveData  = errors.New("Savedata given together with a storage")
 )
 
 var (
@@ -291,3 +343,46 @@ const (
 	TOX_ERR_GET_PORT_OK        ToxErrGetPort = C.TOX_ERR_GET_PORT_OK
 	TOX_ERR_GET_PORT_NOT_BOUND ToxErrGetPort = C.TOX_ERR_GET_PORT_NOT_BOUND
 )
+
+/* === toxencryptsave === */
+const (
+	TOX_PASS_SALT_LENGTH             = C.TOX_PASS_SALT_LENGTH
+	TOX_PASS_KEY_LENGTH              = C.TOX_PASS_KEY_LENGTH
+	TOX_PASS_ENCRYPTION_EXTRA_LENGTH = C.TOX_PASS_ENCRYPTION_EXTRA_LENGTH
+)
+
+type ToxErrKeyDerivation C.enum_TOX_ERR_KEY_DERIVATION
+
+const (
+	TOX_ERR_KEY_DERIVATION_OK     ToxErrKeyDerivation = C.TOX_ERR_KEY_DERIVATION_OK
+	TOX_ERR_KEY_DERIVATION_NULL   ToxErrKeyDerivation = C.TOX_ERR_KEY_DERIVATION_NULL
+	TOX_ERR_KEY_DERIVATION_FAILED ToxErrKeyDerivation = C.TOX_ERR_KEY_DERIVATION_FAILED
+)
+
+type ToxErrEncryption C.enum_TOX_ERR_ENCRYPTION
+
+const (
+	TOX_ERR_ENCRYPTION_OK                    ToxErrEncryption = C.TOX_ERR_ENCRYPTION_OK
+	TOX_ERR_ENCRYPTION_NULL                  ToxErrEncryption = C.TOX_ERR_ENCRYPTION_NULL
+	TOX_ERR_ENCRYPTION_KEY_DERIVATION_FAILED ToxErrEncryption = C.TOX_ERR_ENCRYPTION_KEY_DERIVATION_FAILED
+	TOX_ERR_ENCRYPTION_FAILED                ToxErrEncryption = C.TOX_ERR_ENCRYPTION_FAILED
+)
+
+type ToxErrDecryption C.enum_TOX_ERR_DECRYPTION
+
+const (
+	TOX_ERR_DECRYPTION_OK                    ToxErrDecryption = C.TOX_ERR_DECRYPTION_OK
+	TOX_ERR_DECRYPTION_NULL                  ToxErrDecryption = C.TOX_ERR_DECRYPTION_NULL
+	TOX_ERR_DECRYPTION_INVALID_LENGTH        ToxErrDecryption = C.TOX_ERR_DECRYPTION_INVALID_LENGTH
+	TOX_ERR_DECRYPTION_BAD_FORMAT            ToxErrDecryption = C.TOX_ERR_DECRYPTION_BAD_FORMAT
+	TOX_ERR_DECRYPTION_KEY_DERIVATION_FAILED ToxErrDecryption = C.TOX_ERR_DECRYPTION_KEY_DERIVATION_FAILED
+	TOX_ERR_DECRYPTION_FAILED                ToxErrDecryption = C.TOX_ERR_DECRYPTION_FAILED
+)
+
+type ToxErrGetSalt C.enum_TOX_ERR_GET_SALT
+
+const (
+	TOX_ERR_GET_SALT_OK         ToxErrGetSalt = C.TOX_ERR_GET_SALT_OK
+	TOX_ERR_GET_SALT_NULL       ToxErrGetSalt = C.TOX_ERR_GET_SALT_NULL
+	TOX_ERR_GET_SALT_BAD_FORMAT ToxErrGetSalt = C.TOX_ERR_GET_SALT_BAD_FORMAT
+)
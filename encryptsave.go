@@ -0,0 +1,124 @@
+package gotox
+
+//#include <tox/toxencryptsave.h>
+import "C"
+
+/* passBytes returns a pointer to the first byte of b. toxencryptsave rejects
+ * NULL pointers, so empty slices point to a dummy byte. */
+func passBytes(b []byte) *C.uint8_t {
+	if len(b) == 0 {
+		var dummy C.uint8_t
+		return &dummy
+	}
+	return (*C.uint8_t)(&b[0])
+}
+
+/* IsDataEncrypted reports whether data has the format of data encrypted by
+ * toxencryptsave, e.g. an encrypted savedata. */
+func IsDataEncrypted(data []byte) bool {
+	if len(data) < TOX_PASS_ENCRYPTION_EXTRA_LENGTH {
+		return false
+	}
+	return bool(C.tox_is_data_encrypted((*C.uint8_t)(&data[0])))
+}
+
+/* PassEncrypt encrypts plaintext with a key derived from passphrase. The
+ * result is TOX_PASS_ENCRYPTION_EXTRA_LENGTH bytes longer than plaintext and
+ * can be decrypted by other Tox clients. */
+func PassEncrypt(plaintext []byte, passphrase []byte) ([]byte, error) {
+	ciphertext := make([]byte, len(plaintext)+TOX_PASS_ENCRYPTION_EXTRA_LENGTH)
+
+	var toxErrEncryption C.TOX_ERR_ENCRYPTION
+	C.tox_pass_encrypt(passBytes(plaintext), C.size_t(len(plaintext)), passBytes(passphrase), C.size_t(len(passphrase)),
+		(*C.uint8_t)(&ciphertext[0]), &toxErrEncryption)
+
+	if ToxErrEncryption(toxErrEncryption) != TOX_ERR_ENCRYPTION_OK {
+		return nil, newToxError("PassEncrypt", ToxErrEncryption(toxErrEncryption))
+	}
+
+	return ciphertext, nil
+}
+
+/* PassDecrypt decrypts data encrypted by PassEncrypt or another Tox client. */
+func PassDecrypt(ciphertext []byte, passphrase []byte) ([]byte, error) {
+	if len(ciphertext) < TOX_PASS_ENCRYPTION_EXTRA_LENGTH {
+		return nil, newToxError("PassDecrypt", TOX_ERR_DECRYPTION_INVALID_LENGTH)
+	}
+
+	plaintext := make([]byte, len(ciphertext)-TOX_PASS_ENCRYPTION_EXTRA_LENGTH)
+
+	var toxErrDecryption C.TOX_ERR_DECRYPTION
+	C.tox_pass_decrypt((*C.uint8_t)(&ciphertext[0]), C.size_t(len(ciphertext)), passBytes(passphrase), C.size_t(len(passphrase)),
+		passBytes(plaintext), &toxErrDecryption)
+
+	if ToxErrDecryption(toxErrDecryption) != TOX_ERR_DECRYPTION_OK {
+		return nil, newToxError("PassDecrypt", ToxErrDecryption(toxErrDecryption))
+	}
+
+	return plaintext, nil
+}
+
+/* GetSalt returns the salt of encrypted data. */
+func GetSalt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < TOX_PASS_ENCRYPTION_EXTRA_LENGTH {
+		return nil, newToxError("GetSalt", TOX_ERR_GET_SALT_BAD_FORMAT)
+	}
+
+	salt := make([]byte, TOX_PASS_SALT_LENGTH)
+
+	var toxErrGetSalt C.TOX_ERR_GET_SALT
+	C.tox_get_salt((*C.uint8_t)(&ciphertext[0]), (*C.uint8_t)(&salt[0]), &toxErrGetSalt)
+
+	if ToxErrGetSalt(toxErrGetSalt) != TOX_ERR_GET_SALT_OK {
+		return nil, newToxError("GetSalt", ToxErrGetSalt(toxErrGetSalt))
+	}
+
+	return salt, nil
+}
+
+/* NewEncrypted is like New, but decrypts the savedata with passphrase first if
+ * it is encrypted. options is not modified. */
+func NewEncrypted(options *Options, passphrase []byte) (*Tox, error) {
+	if options != nil && options.Storage != nil {
+		if err := options.Validate(); err != nil {
+			return nil, err
+		}
+		return newFromStorage(options, func(options *Options) (*Tox, error) {
+			return NewEncrypted(options, passphrase)
+		})
+	}
+	if options == nil || !IsDataEncrypted(options.SaveData) {
+		return New(options)
+	}
+
+	plaintext, err := PassDecrypt(options.SaveData, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := *options
+	decrypted.SaveData = plaintext
+	t, err := New(&decrypted)
+
+	// New wipes its C copy of the savedata, wipe this one too
+	for i := range plaintext {
+		plaintext[i] = 0
+	}
+
+	return t, err
+}
+
+/* GetEncryptedSavedata returns the savedata encrypted with passphrase, in the
+ * format other Tox clients use for encrypted profiles. */
+func (t *Tox) GetEncryptedSavedata(passphrase []byte) ([]byte, error) {
+	plaintext, err := t.GetSavedata()
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := PassEncrypt(plaintext, passphrase)
+	for i := range plaintext {
+		plaintext[i] = 0
+	}
+	return ciphertext, err
+}
@@ -0,0 +1,541 @@
+package gotox
+
+/* ToxError is returned by the bindings whenever toxcore reports a specific
+ * error code. Op is the name of the failing binding (e.g. "FriendSendMessage")
+ * and Code is the ToxErr* value toxcore returned.
+ *
+ * Use errors.As to inspect the code, or errors.Is to compare against either a
+ * ToxErr* constant or one of the older Err* sentinels:
+ *
+ *	if errors.Is(err, gotox.TOX_ERR_FRIEND_SEND_MESSAGE_FRIEND_NOT_CONNECTED) { ... }
+ *	if errors.Is(err, gotox.ErrFuncFail) { ... } // still works
+ */
+type ToxError struct {
+	Op   string
+	Code ToxErrCode
+}
+
+/* ToxErrCode is implemented by every ToxErr* enum type. */
+type ToxErrCode interface {
+	error
+
+	// sentinel returns the Err* variable that was returned for this code
+	// before ToxError was introduced.
+	sentinel() error
+}
+
+func (e *ToxError) Error() string {
+	return e.Op + ": " + e.Code.Error()
+}
+
+/* Unwrap returns the toxcore error code, so that errors.Is and errors.As can
+ * match the ToxErr* constants directly. */
+func (e *ToxError) Unwrap() error {
+	return e.Code
+}
+
+/* Is reports whether target is the Err* sentinel this error used to be
+ * reported as. */
+func (e *ToxError) Is(target error) bool {
+	return target != nil && target == e.Code.sentinel()
+}
+
+func newToxError(op string, code ToxErrCode) error {
+	return &ToxError{Op: op, Code: code}
+}
+
+func (e ToxErrOptionsNew) Error() string {
+	switch e {
+	case TOX_ERR_OPTIONS_NEW_OK:
+		return "No error"
+	case TOX_ERR_OPTIONS_NEW_MALLOC:
+		return "Failed to allocate the options struct"
+	}
+	return "Unknown options error"
+}
+
+func (e ToxErrOptionsNew) sentinel() error {
+	return ErrFuncFail
+}
+
+func (e ToxErrNew) Error() string {
+	switch e {
+	case TOX_ERR_NEW_OK:
+		return "No error"
+	case TOX_ERR_NEW_NULL:
+		return "A required argument was NULL"
+	case TOX_ERR_NEW_MALLOC:
+		return ErrNewMalloc.Error()
+	case TOX_ERR_NEW_PORT_ALLOC:
+		return ErrNewPortAlloc.Error()
+	case TOX_ERR_NEW_PROXY_BAD_TYPE:
+		return "Invalid proxy type"
+	case TOX_ERR_NEW_PROXY_BAD_HOST:
+		return "Invalid proxy host"
+	case TOX_ERR_NEW_PROXY_BAD_PORT:
+		return "Invalid proxy port"
+	case TOX_ERR_NEW_PROXY_NOT_FOUND:
+		return "The proxy address could not be resolved"
+	case TOX_ERR_NEW_LOAD_ENCRYPTED:
+		return ErrNewLoadEnc.Error()
+	case TOX_ERR_NEW_LOAD_BAD_FORMAT:
+		return ErrNewLoadBadFormat.Error()
+	}
+	return "Unknown error creating Tox"
+}
+
+func (e ToxErrNew) sentinel() error {
+	switch e {
+	case TOX_ERR_NEW_NULL:
+		return ErrArgs
+	case TOX_ERR_NEW_MALLOC:
+		return ErrNewMalloc
+	case TOX_ERR_NEW_PORT_ALLOC:
+		return ErrNewPortAlloc
+	case TOX_ERR_NEW_PROXY_BAD_TYPE, TOX_ERR_NEW_PROXY_BAD_HOST, TOX_ERR_NEW_PROXY_BAD_PORT, TOX_ERR_NEW_PROXY_NOT_FOUND:
+		return ErrNewProxy
+	case TOX_ERR_NEW_LOAD_ENCRYPTED:
+		return ErrNewLoadEnc
+	case TOX_ERR_NEW_LOAD_BAD_FORMAT:
+		return ErrNewLoadBadFormat
+	}
+	return ErrUnknown
+}
+
+func (e ToxErrBootstrap) Error() string {
+	switch e {
+	case TOX_ERR_BOOTSTRAP_OK:
+		return "No error"
+	case TOX_ERR_BOOTSTRAP_NULL:
+		return "A required argument was NULL"
+	case TOX_ERR_BOOTSTRAP_BAD_HOST:
+		return "The address could not be resolved"
+	case TOX_ERR_BOOTSTRAP_BAD_PORT:
+		return "The port is invalid"
+	}
+	return "Unknown bootstrap error"
+}
+
+func (e ToxErrBootstrap) sentinel() error {
+	if e == TOX_ERR_BOOTSTRAP_NULL {
+		return ErrArgs
+	}
+	return ErrFuncFail
+}
+
+func (e ToxErrFriendAdd) Error() string {
+	switch e {
+	case TOX_ERR_FRIEND_ADD_OK:
+		return "No error"
+	case TOX_ERR_FRIEND_ADD_NULL:
+		return "A required argument was NULL"
+	}
+	if err := e.sentinel(); err != ErrFuncFail {
+		return err.Error()
+	}
+	return "Unknown friend add error"
+}
+
+func (e ToxErrFriendAdd) sentinel() error {
+	switch e {
+	case TOX_ERR_FRIEND_ADD_NULL:
+		return ErrArgs
+	case TOX_ERR_FRIEND_ADD_TOO_LONG:
+		return ErrFriendAddTooLong
+	case TOX_ERR_FRIEND_ADD_NO_MESSAGE:
+		return ErrFriendAddNoMessage
+	case TOX_ERR_FRIEND_ADD_OWN_KEY:
+		return ErrFriendAddOwnKey
+	case TOX_ERR_FRIEND_ADD_ALREADY_SENT:
+		return ErrFriendAddAlreadySent
+	case TOX_ERR_FRIEND_ADD_BAD_CHECKSUM:
+		return ErrFriendAddBadChecksum
+	case TOX_ERR_FRIEND_ADD_SET_NEW_NOSPAM:
+		return ErrFriendAddSetNewNospam
+	case TOX_ERR_FRIEND_ADD_MALLOC:
+		return ErrFriendAddNoMem
+	}
+	return ErrFuncFail
+}
+
+func (e ToxErrFriendByPublicKey) Error() string {
+	switch e {
+	case TOX_ERR_FRIEND_BY_PUBLIC_KEY_OK:
+		return "No error"
+	case TOX_ERR_FRIEND_BY_PUBLIC_KEY_NULL:
+		return "A required argument was NULL"
+	case TOX_ERR_FRIEND_BY_PUBLIC_KEY_NOT_FOUND:
+		return "No friend with the given public key exists"
+	}
+	return "Unknown friend lookup error"
+}
+
+func (e ToxErrFriendByPublicKey) sentinel() error {
+	if e == TOX_ERR_FRIEND_BY_PUBLIC_KEY_NULL {
+		return ErrArgs
+	}
+	return ErrFuncFail
+}
+
+func (e ToxErrFriendGetPublicKey) Error() string {
+	switch e {
+	case TOX_ERR_FRIEND_GET_PUBLIC_KEY_OK:
+		return "No error"
+	case TOX_ERR_FRIEND_GET_PUBLIC_KEY_FRIEND_NOT_FOUND:
+		return "Friend not found"
+	}
+	return "Unknown public key error"
+}
+
+func (e ToxErrFriendGetPublicKey) sentinel() error {
+	if e == TOX_ERR_FRIEND_GET_PUBLIC_KEY_FRIEND_NOT_FOUND {
+		return ErrArgs
+	}
+	return ErrFuncFail
+}
+
+func (e ToxErrFriendDelete) Error() string {
+	switch e {
+	case TOX_ERR_FRIEND_DELETE_OK:
+		return "No error"
+	case TOX_ERR_FRIEND_DELETE_FRIEND_NOT_FOUND:
+		return "Friend not found"
+	}
+	return "Unknown friend delete error"
+}
+
+func (e ToxErrFriendDelete) sentinel() error {
+	if e == TOX_ERR_FRIEND_DELETE_FRIEND_NOT_FOUND {
+		return ErrArgs
+	}
+	return ErrFuncFail
+}
+
+func (e ToxErrFriendQuery) Error() string {
+	switch e {
+	case TOX_ERR_FRIEND_QUERY_OK:
+		return "No error"
+	case TOX_ERR_FRIEND_QUERY_NULL:
+		return "A required argument was NULL"
+	case TOX_ERR_FRIEND_QUERY_FRIEND_NOT_FOUND:
+		return "Friend not found"
+	}
+	return "Unknown friend query error"
+}
+
+func (e ToxErrFriendQuery) sentinel() error {
+	return ErrFuncFail
+}
+
+func (e ToxErrSetInfo) Error() string {
+	switch e {
+	case TOX_ERR_SET_INFO_OK:
+		return "No error"
+	case TOX_ERR_SET_INFO_NULL:
+		return "A required argument was NULL"
+	case TOX_ERR_SET_INFO_TOO_LONG:
+		return "Information too long"
+	}
+	return "Unknown set info error"
+}
+
+func (e ToxErrSetInfo) sentinel() error {
+	return ErrFuncFail
+}
+
+func (e ToxErrSetTyping) Error() string {
+	switch e {
+	case TOX_ERR_SET_TYPING_OK:
+		return "No error"
+	case TOX_ERR_SET_TYPING_FRIEND_NOT_FOUND:
+		return "Friend not found"
+	}
+	return "Unknown set typing error"
+}
+
+func (e ToxErrSetTyping) sentinel() error {
+	return ErrFuncFail
+}
+
+func (e ToxErrFriendSendMessage) Error() string {
+	switch e {
+	case TOX_ERR_FRIEND_SEND_MESSAGE_OK:
+		return "No error"
+	case TOX_ERR_FRIEND_SEND_MESSAGE_NULL:
+		return "A required argument was NULL"
+	case TOX_ERR_FRIEND_SEND_MESSAGE_FRIEND_NOT_FOUND:
+		return "Friend not found"
+	case TOX_ERR_FRIEND_SEND_MESSAGE_FRIEND_NOT_CONNECTED:
+		return "Friend not connected"
+	case TOX_ERR_FRIEND_SEND_MESSAGE_SENDQ:
+		return "Failed to allocate space in the send queue"
+	case TOX_ERR_FRIEND_SEND_MESSAGE_TOO_LONG:
+		return "Message too long"
+	case TOX_ERR_FRIEND_SEND_MESSAGE_EMPTY:
+		return "Empty message"
+	}
+	return "Unknown send message error"
+}
+
+func (e ToxErrFriendSendMessage) sentinel() error {
+	return ErrFuncFail
+}
+
+func (e ToxErrFriendGetLastOnline) Error() string {
+	switch e {
+	case TOX_ERR_FRIEND_GET_LAST_ONLINE_OK:
+		return "No error"
+	case TOX_ERR_FRIEND_GET_LAST_ONLINE_FRIEND_NOT_FOUND:
+		return "Friend not found"
+	}
+	return "Unknown last online error"
+}
+
+func (e ToxErrFriendGetLastOnline) sentinel() error {
+	return ErrFuncFail
+}
+
+func (e ToxErrFileControl) Error() string {
+	switch e {
+	case TOX_ERR_FILE_CONTROL_OK:
+		return "No error"
+	case TOX_ERR_FILE_CONTROL_FRIEND_NOT_FOUND:
+		return "Friend not found"
+	case TOX_ERR_FILE_CONTROL_FRIEND_NOT_CONNECTED:
+		return "Friend not connected"
+	case TOX_ERR_FILE_CONTROL_NOT_FOUND:
+		return "No file transfer with the given file number"
+	case TOX_ERR_FILE_CONTROL_NOT_PAUSED:
+		return "The transfer is not paused"
+	case TOX_ERR_FILE_CONTROL_DENIED:
+		return "The transfer was paused by the friend"
+	case TOX_ERR_FILE_CONTROL_ALREADY_PAUSED:
+		return "The transfer is already paused"
+	case TOX_ERR_FILE_CONTROL_SENDQ:
+		return "Packet queue is full"
+	}
+	return "Unknown file control error"
+}
+
+func (e ToxErrFileControl) sentinel() error {
+	return ErrFuncFail
+}
+
+func (e ToxErrFileSeek) Error() string {
+	switch e {
+	case TOX_ERR_FILE_SEEK_OK:
+		return "No error"
+	case TOX_ERR_FILE_SEEK_FRIEND_NOT_FOUND:
+		return "Friend not found"
+	case TOX_ERR_FILE_SEEK_FRIEND_NOT_CONNECTED:
+		return "Friend not connected"
+	case TOX_ERR_FILE_SEEK_NOT_FOUND:
+		return "No file transfer with the given file number"
+	case TOX_ERR_FILE_SEEK_DENIED:
+		return "The transfer is not in a state where seeking is allowed"
+	case TOX_ERR_FILE_SEEK_INVALID_POSITION:
+		return "Seek position was invalid"
+	case TOX_ERR_FILE_SEEK_SENDQ:
+		return "Packet queue is full"
+	}
+	return "Unknown file seek error"
+}
+
+func (e ToxErrFileSeek) sentinel() error {
+	return ErrFuncFail
+}
+
+func (e ToxErrFileGet) Error() string {
+	switch e {
+	case TOX_ERR_FILE_GET_OK:
+		return "No error"
+	case TOX_ERR_FILE_GET_NULL:
+		return "A required argument was NULL"
+	case TOX_ERR_FILE_GET_FRIEND_NOT_FOUND:
+		return "Friend not found"
+	case TOX_ERR_FILE_GET_NOT_FOUND:
+		return "No file transfer with the given file number"
+	}
+	return "Unknown file get error"
+}
+
+func (e ToxErrFileGet) sentinel() error {
+	return ErrFuncFail
+}
+
+func (e ToxErrFileSend) Error() string {
+	switch e {
+	case TOX_ERR_FILE_SEND_OK:
+		return "No error"
+	case TOX_ERR_FILE_SEND_NULL:
+		return "A required argument was NULL"
+	case TOX_ERR_FILE_SEND_FRIEND_NOT_FOUND:
+		return "Friend not found"
+	case TOX_ERR_FILE_SEND_FRIEND_NOT_CONNECTED:
+		return "Friend not connected"
+	case TOX_ERR_FILE_SEND_NAME_TOO_LONG:
+		return "Filename too long"
+	case TOX_ERR_FILE_SEND_TOO_MANY:
+		return "Too many ongoing transfers"
+	}
+	return "Unknown file send error"
+}
+
+func (e ToxErrFileSend) sentinel() error {
+	return ErrFuncFail
+}
+
+func (e ToxErrFileSendChunk) Error() string {
+	switch e {
+	case TOX_ERR_FILE_SEND_CHUNK_OK:
+		return "No error"
+	case TOX_ERR_FILE_SEND_CHUNK_NULL:
+		return "A required argument was NULL"
+	case TOX_ERR_FILE_SEND_CHUNK_FRIEND_NOT_FOUND:
+		return "Friend not found"
+	case TOX_ERR_FILE_SEND_CHUNK_FRIEND_NOT_CONNECTED:
+		return "Friend not connected"
+	case TOX_ERR_FILE_SEND_CHUNK_NOT_FOUND:
+		return "No file transfer with the given file number"
+	case TOX_ERR_FILE_SEND_CHUNK_NOT_TRANSFERRING:
+		return "The transfer is not in a transferring state"
+	case TOX_ERR_FILE_SEND_CHUNK_INVALID_LENGTH:
+		return "Invalid chunk length"
+	case TOX_ERR_FILE_SEND_CHUNK_SENDQ:
+		return "Packet queue is full"
+	case TOX_ERR_FILE_SEND_CHUNK_WRONG_POSITION:
+		return "Chunk position does not match the requested position"
+	}
+	return "Unknown send chunk error"
+}
+
+func (e ToxErrFileSendChunk) sentinel() error {
+	return ErrFuncFail
+}
+
+func (e ToxErrFriendCustomPacket) Error() string {
+	switch e {
+	case TOX_ERR_FRIEND_CUSTOM_PACKET_OK:
+		return "No error"
+	case TOX_ERR_FRIEND_CUSTOM_PACKET_NULL:
+		return "A required argument was NULL"
+	case TOX_ERR_FRIEND_CUSTOM_PACKET_FRIEND_NOT_FOUND:
+		return "Friend not found"
+	case TOX_ERR_FRIEND_CUSTOM_PACKET_FRIEND_NOT_CONNECTED:
+		return "Friend not connected"
+	case TOX_ERR_FRIEND_CUSTOM_PACKET_INVALID:
+		return "The first byte of data is not in the allowed range"
+	case TOX_ERR_FRIEND_CUSTOM_PACKET_EMPTY:
+		return "Empty packet"
+	case TOX_ERR_FRIEND_CUSTOM_PACKET_TOO_LONG:
+		return "Packet too long"
+	case TOX_ERR_FRIEND_CUSTOM_PACKET_SENDQ:
+		return "Packet queue is full"
+	}
+	return "Unknown custom packet error"
+}
+
+func (e ToxErrFriendCustomPacket) sentinel() error {
+	if e == TOX_ERR_FRIEND_CUSTOM_PACKET_NULL {
+		return ErrArgs
+	}
+	return ErrFuncFail
+}
+
+func (e ToxErrGetPort) Error() string {
+	switch e {
+	case TOX_ERR_GET_PORT_OK:
+		return "No error"
+	case TOX_ERR_GET_PORT_NOT_BOUND:
+		return "The instance was not bound to any port"
+	}
+	return "Unknown get port error"
+}
+
+func (e ToxErrGetPort) sentinel() error {
+	return ErrFuncFail
+}
+
+func (e ToxErrKeyDerivation) Error() string {
+	switch e {
+	case TOX_ERR_KEY_DERIVATION_OK:
+		return "No error"
+	case TOX_ERR_KEY_DERIVATION_NULL:
+		return "A required argument was NULL"
+	case TOX_ERR_KEY_DERIVATION_FAILED:
+		return "Key derivation failed, probably out of memory"
+	}
+	return "Unknown key derivation error"
+}
+
+func (e ToxErrKeyDerivation) sentinel() error {
+	if e == TOX_ERR_KEY_DERIVATION_NULL {
+		return ErrArgs
+	}
+	return ErrFuncFail
+}
+
+func (e ToxErrEncryption) Error() string {
+	switch e {
+	case TOX_ERR_ENCRYPTION_OK:
+		return "No error"
+	case TOX_ERR_ENCRYPTION_NULL:
+		return "A required argument was NULL"
+	case TOX_ERR_ENCRYPTION_KEY_DERIVATION_FAILED:
+		return "Key derivation failed, probably out of memory"
+	case TOX_ERR_ENCRYPTION_FAILED:
+		return "Encryption failed"
+	}
+	return "Unknown encryption error"
+}
+
+func (e ToxErrEncryption) sentinel() error {
+	if e == TOX_ERR_ENCRYPTION_NULL {
+		return ErrArgs
+	}
+	return ErrFuncFail
+}
+
+func (e ToxErrDecryption) Error() string {
+	switch e {
+	case TOX_ERR_DECRYPTION_OK:
+		return "No error"
+	case TOX_ERR_DECRYPTION_NULL:
+		return "A required argument was NULL"
+	case TOX_ERR_DECRYPTION_INVALID_LENGTH:
+		return "The data is too short to be encrypted data"
+	case TOX_ERR_DECRYPTION_BAD_FORMAT:
+		return "The data is not in the encrypted format"
+	case TOX_ERR_DECRYPTION_KEY_DERIVATION_FAILED:
+		return "Key derivation failed, probably out of memory"
+	case TOX_ERR_DECRYPTION_FAILED:
+		return "Decryption failed, the passphrase is probably wrong"
+	}
+	return "Unknown decryption error"
+}
+
+func (e ToxErrDecryption) sentinel() error {
+	if e == TOX_ERR_DECRYPTION_NULL || e == TOX_ERR_DECRYPTION_INVALID_LENGTH {
+		return ErrArgs
+	}
+	return ErrFuncFail
+}
+
+func (e ToxErrGetSalt) Error() string {
+	switch e {
+	case TOX_ERR_GET_SALT_OK:
+		return "No error"
+	case TOX_ERR_GET_SALT_NULL:
+		return "A required argument was NULL"
+	case TOX_ERR_GET_SALT_BAD_FORMAT:
+		return "The data is not in the encrypted format"
+	}
+	return "Unknown get salt error"
+}
+
+func (e ToxErrGetSalt) sentinel() error {
+	if e == TOX_ERR_GET_SALT_NULL {
+		return ErrArgs
+	}
+	return ErrFuncFail
+}
@@ -0,0 +1,296 @@
+package gotox
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+/* Event is implemented by all event types delivered on the channel returned by
+ * Events. Use a type switch to handle the events you are interested in. */
+type Event interface {
+	toxEvent()
+}
+
+/* SelfConnectionStatusEvent is the event form of OnSelfConnectionStatusChanges. */
+type SelfConnectionStatusEvent struct {
+	Status ToxConnection
+}
+
+/* FriendNameEvent is the event form of OnFriendNameChanges. */
+type FriendNameEvent struct {
+	FriendNumber uint32
+	Name         string
+}
+
+/* FriendStatusMessageEvent is the event form of OnFriendStatusMessageChanges. */
+type FriendStatusMessageEvent struct {
+	FriendNumber uint32
+	Message      string
+}
+
+/* FriendStatusEvent is the event form of OnFriendStatusChanges. */
+type FriendStatusEvent struct {
+	FriendNumber uint32
+	Status       ToxUserStatus
+}
+
+/* FriendConnectionStatusEvent is the event form of
+ * OnFriendConnectionStatusChanges. */
+type FriendConnectionStatusEvent struct {
+	FriendNumber uint32
+	Status       ToxConnection
+}
+
+/* FriendTypingEvent is the event form of OnFriendTypingChanges. */
+type FriendTypingEvent struct {
+	FriendNumber uint32
+	IsTyping     bool
+}
+
+/* FriendReadReceiptEvent is the event form of OnFriendReadReceipt. */
+type FriendReadReceiptEvent struct {
+	FriendNumber uint32
+	MessageID    uint32
+}
+
+/* FriendRequestEvent is the event form of OnFriendRequest. */
+type FriendRequestEvent struct {
+	PublicKey PublicKey
+	Message   string
+}
+
+/* FriendMessageEvent is the event form of OnFriendMessage. */
+type FriendMessageEvent struct {
+	FriendNumber uint32
+	MessageType  ToxMessageType
+	Message      string
+}
+
+/* FileRecvControlEvent is the event form of OnFileRecvControl. */
+type FileRecvControlEvent struct {
+	FriendNumber uint32
+	FileNumber   uint32
+	Control      ToxFileControl
+}
+
+/* FileChunkRequestEvent is the event form of OnFileChunkRequest. */
+type FileChunkRequestEvent struct {
+	FriendNumber uint32
+	FileNumber   uint32
+	Position     uint64
+	Length       uint64
+}
+
+/* FileRecvEvent is the event form of OnFileRecv. */
+type FileRecvEvent struct {
+	FriendNumber uint32
+	FileNumber   uint32
+	Kind         ToxFileKind
+	FileSize     uint64
+	Filename     string
+}
+
+/* FileRecvChunkEvent is the event form of OnFileRecvChunk. */
+type FileRecvChunkEvent struct {
+	FriendNumber uint32
+	FileNumber   uint32
+	Position     uint64
+	Data         []byte
+}
+
+/* FriendLossyPacketEvent is the event form of OnFriendLossyPacket. */
+type FriendLossyPacketEvent struct {
+	FriendNumber uint32
+	Data         []byte
+}
+
+/* FriendLosslessPacketEvent is the event form of OnFriendLosslessPacket. */
+type FriendLosslessPacketEvent struct {
+	FriendNumber uint32
+	Data         []byte
+}
+
+func (SelfConnectionStatusEvent) toxEvent()   {}
+func (FriendNameEvent) toxEvent()             {}
+func (FriendStatusMessageEvent) toxEvent()    {}
+func (FriendStatusEvent) toxEvent()           {}
+func (FriendConnectionStatusEvent) toxEvent() {}
+func (FriendTypingEvent) toxEvent()           {}
+func (FriendReadReceiptEvent) toxEvent()      {}
+func (FriendRequestEvent) toxEvent()          {}
+func (FriendMessageEvent) toxEvent()          {}
+func (FileRecvControlEvent) toxEvent()        {}
+func (FileChunkRequestEvent) toxEvent()       {}
+func (FileRecvEvent) toxEvent()               {}
+func (FileRecvChunkEvent) toxEvent()          {}
+func (FriendLossyPacketEvent) toxEvent()      {}
+func (FriendLosslessPacketEvent) toxEvent()   {}
+
+/* EventOverflow defines what happens when the event channel is full. */
+type EventOverflow int
+
+const (
+	/* Block Iterate until the consumer has read from the channel. */
+	EVENT_OVERFLOW_BLOCK EventOverflow = iota
+	/* Discard the oldest buffered event to make room for the new one. Requires
+	 * a BufferSize greater than zero. */
+	EVENT_OVERFLOW_DROP_OLDEST
+	/* Discard the new event and return ErrEventOverflow from Iterate. Run
+	 * keeps running. */
+	EVENT_OVERFLOW_ERROR
+)
+
+/* EventOptions configures the channel returned by Events. */
+type EventOptions struct {
+	/* The capacity of the event channel. */
+	BufferSize int
+
+	/* What to do with new events while the channel is full. */
+	Overflow EventOverflow
+}
+
+var (
+	ErrEventOverflow = errors.New("Event channel full, events were dropped")
+)
+
+type eventStream struct {
+	ch       chan Event
+	overflow EventOverflow
+
+	// Closed by close, so blocked deliveries give up before ch is closed
+	done chan struct{}
+
+	// Protects closed and senders
+	mtx     sync.Mutex
+	closed  bool
+	senders sync.WaitGroup
+
+	// Serializes the deliveries, so events stay in order
+	sendMtx sync.Mutex
+}
+
+/* Events returns a channel that receives an Event for every callback toxcore
+ * fires during Iterate. The callbacks registered with the Callback* functions
+ * are still called first; the events are sent once Iterate has released the
+ * Tox lock, so consumers may call any method of Tox.
+ * The channel is created on the first call using options (nil means an
+ * unbuffered, blocking channel); later calls return the same channel and ignore
+ * options. The channel is closed by Kill. */
+func (t *Tox) Events(options *EventOptions) (<-chan Event, error) {
+	if err := t.lock(); err != nil {
+		return nil, err
+	}
+	defer t.unlock()
+
+	if t.events != nil {
+		return t.events.ch, nil
+	}
+
+	if options == nil {
+		options = &EventOptions{}
+	}
+	if options.BufferSize < 0 || (options.Overflow == EVENT_OVERFLOW_DROP_OLDEST && options.BufferSize == 0) {
+		return nil, ErrArgs
+	}
+
+	t.events = &eventStream{
+		ch:       make(chan Event, options.BufferSize),
+		overflow: options.Overflow,
+		done:     make(chan struct{}),
+	}
+	t.registerAllHooks()
+
+	return t.events.ch, nil
+}
+
+/* queueEvent records ev for delivery at the end of Iterate. It is called by the
+ * hooks while the Tox lock is held. */
+func (t *Tox) queueEvent(ev Event) {
+	if t.events != nil {
+		t.pendingEvents = append(t.pendingEvents, ev)
+	}
+}
+
+/* takeEvents returns the event stream and the events queued since the last
+ * call. The Tox lock must be held. */
+func (t *Tox) takeEvents() (*eventStream, []Event) {
+	events := t.pendingEvents
+	t.pendingEvents = nil
+	return t.events, events
+}
+
+/* deliver sends events to the channel according to the overflow policy. It
+ * returns ErrEventOverflow if events had to be discarded, and ctx.Err() if ctx
+ * is cancelled while blocked on a full channel. Once the stream is closed the
+ * remaining events are discarded. */
+func (s *eventStream) deliver(ctx context.Context, events []Event) error {
+	if s == nil || len(events) == 0 {
+		return nil
+	}
+
+	s.mtx.Lock()
+	if s.closed {
+		s.mtx.Unlock()
+		return nil
+	}
+	s.senders.Add(1)
+	s.mtx.Unlock()
+	defer s.senders.Done()
+
+	s.sendMtx.Lock()
+	defer s.sendMtx.Unlock()
+
+	var err error
+	for _, ev := range events {
+		switch s.overflow {
+		case EVENT_OVERFLOW_DROP_OLDEST:
+			for sent := false; !sent; {
+				select {
+				case s.ch <- ev:
+					sent = true
+				case <-s.done:
+					return nil
+				default:
+					select {
+					case <-s.ch:
+					default:
+					}
+				}
+			}
+		case EVENT_OVERFLOW_ERROR:
+			select {
+			case s.ch <- ev:
+			case <-s.done:
+				return nil
+			default:
+				err = ErrEventOverflow
+			}
+		default:
+			select {
+			case s.ch <- ev:
+			case <-s.done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	return err
+}
+
+/* close stops the deliveries in progress and closes the event channel. */
+func (s *eventStream) close() {
+	s.mtx.Lock()
+	if s.closed {
+		s.mtx.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.done)
+	s.mtx.Unlock()
+
+	s.senders.Wait()
+	close(s.ch)
+}
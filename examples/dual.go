@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/hex"
 	"fmt"
 	"github.com/codedust/go-tox"
 	"time"
@@ -10,7 +9,7 @@ import (
 type Server struct {
 	Address   string
 	Port      uint16
-	PublicKey []byte
+	PublicKey gotox.PublicKey
 }
 
 var counter int = 0
@@ -29,10 +28,10 @@ func main() {
 	bob.SelfSetName("BobBot")
 
 	aliceAddr, _ := alice.SelfGetAddress()
-	fmt.Println("[ID alice]", hex.EncodeToString(aliceAddr))
+	fmt.Println("[ID alice]", aliceAddr)
 
 	bobAddr, _ := bob.SelfGetAddress()
-	fmt.Println("[ID bob]", hex.EncodeToString(bobAddr))
+	fmt.Println("[ID bob]", bobAddr)
 
 	// We can set the same callback function for both *Tox instances
 	bob.CallbackFriendRequest(onFriendRequest)
@@ -49,7 +48,7 @@ func main() {
 	 * Use more than one node in a real world szenario. This example relies one
 	 * the following node to be up.
 	 */
-	pubkey, _ := hex.DecodeString("B75583B6D967DB8AD7C6D3B6F9318194BCC79B2FEF18F69E2DF275B779E7AA30")
+	pubkey, _ := gotox.ParsePublicKey("B75583B6D967DB8AD7C6D3B6F9318194BCC79B2FEF18F69E2DF275B779E7AA30")
 	server := &Server{"maggie.prok.pw", 33445, pubkey}
 
 	err = alice.Bootstrap(server.Address, server.Port, server.PublicKey)
@@ -104,10 +103,10 @@ func main() {
 	}
 }
 
-func onFriendRequest(t *gotox.Tox, publicKey []byte, message string) {
+func onFriendRequest(t *gotox.Tox, publicKey gotox.PublicKey, message string) {
 	counter++
 	name, _ := t.SelfGetName()
-	fmt.Printf("[%s] New friend request from %s\n", name, hex.EncodeToString(publicKey))
+	fmt.Printf("[%s] New friend request from %s\n", name, publicKey.String())
 
 	// Auto-accept friend request
 	friendnumber, err := t.FriendAddNorequest(publicKey)
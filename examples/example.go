@@ -1,21 +1,19 @@
 package main
 
 import (
-	"encoding/hex"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"github.com/codedust/go-tox"
-	"io/ioutil"
 	"os"
 	"os/signal"
-	"time"
 )
 
 type Server struct {
 	Address   string
 	Port      uint16
-	PublicKey []byte
+	PublicKey gotox.PublicKey
 }
 
 const MAX_AVATAR_SIZE = 65536 // see github.com/Tox/Tox-STS/blob/master/STS.md#avatars
@@ -45,20 +43,22 @@ func main() {
 		return
 	}
 
-	savedata, err := loadData(filepath)
-	if err == nil {
+	// The profile store saves the profile whenever it changes
+	store, err := gotox.NewProfileStore(&gotox.ProfileStoreOptions{Path: filepath, Backups: 2})
+	if err != nil {
+		panic(err)
+	}
+
+	savedata, err := store.Load()
+	if err != nil {
+		panic(err)
+	}
+
+	if savedata != nil {
 		fmt.Println("[INFO] Loading Tox profile from savedata...")
-		options = &gotox.Options{
-			IPv6Enabled:  true,
-			UDPEnabled:   true,
-			ProxyType:    gotox.TOX_PROXY_TYPE_NONE,
-			ProxyHost:    "127.0.0.1",
-			ProxyPort:    5555,
-			StartPort:    0,
-			EndPort:      0,
-			TcpPort:      0, // only enable TCP server if your client provides an option to disable it
-			SaveDataType: gotox.TOX_SAVEDATA_TYPE_TOX_SAVE,
-			SaveData:     savedata}
+		options = gotox.DefaultOptions()
+		options.SaveDataType = gotox.TOX_SAVEDATA_TYPE_TOX_SAVE
+		options.SaveData = savedata
 	} else {
 		fmt.Println("[INFO] Creating new Tox profile...")
 		options = nil // default options
@@ -70,13 +70,17 @@ func main() {
 		panic(err)
 	}
 
+	if err := store.Attach(tox); err != nil {
+		panic(err)
+	}
+
 	if newToxInstance {
 		tox.SelfSetName("gotoxBot")
 		tox.SelfSetStatusMessage("gotox is cool!")
 	}
 
 	addr, _ := tox.SelfGetAddress()
-	fmt.Println("ID: ", hex.EncodeToString(addr))
+	fmt.Println("ID: ", addr)
 
 	err = tox.SelfSetStatus(gotox.TOX_USERSTATUS_NONE)
 
@@ -92,7 +96,7 @@ func main() {
 	 * Use more than one node in a real world szenario. This example relies one
 	 * the following node to be up.
 	 */
-	pubkey, _ := hex.DecodeString("04119E835DF3E78BACF0F84235B300546AF8B936F035185E2A8E9E0A67C8924F")
+	pubkey, _ := gotox.ParsePublicKey("04119E835DF3E78BACF0F84235B300546AF8B936F035185E2A8E9E0A67C8924F")
 	server := &Server{"144.76.60.215", 33445, pubkey}
 
 	err = tox.Bootstrap(server.Address, server.Port, server.PublicKey)
@@ -100,30 +104,24 @@ func main() {
 		panic(err)
 	}
 
-	isRunning := true
-
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	ticker := time.NewTicker(25 * time.Millisecond)
-
-	for isRunning {
-		select {
-		case <-c:
-			fmt.Printf("\nSaving...\n")
-			if err := saveData(tox, filepath); err != nil {
-				fmt.Println("[ERROR]", err)
-			}
-			fmt.Println("Killing")
-			isRunning = false
-			tox.Kill()
-		case <-ticker.C:
-			tox.Iterate()
-		}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Iterate until we receive SIGINT
+	if err := tox.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Println("[ERROR]", err)
+	}
+
+	fmt.Printf("\nSaving...\n")
+	if err := store.Flush(); err != nil {
+		fmt.Println("[ERROR]", err)
 	}
+	fmt.Println("Killing")
+	tox.Kill()
 }
 
-func onFriendRequest(t *gotox.Tox, publicKey []byte, message string) {
-	fmt.Printf("New friend request from %s\n", hex.EncodeToString(publicKey))
+func onFriendRequest(t *gotox.Tox, publicKey gotox.PublicKey, message string) {
+	fmt.Printf("New friend request from %s\n", publicKey.String())
 	fmt.Printf("With message: %v\n", message)
 	// Auto-accept friend request
 	t.FriendAddNorequest(publicKey)
@@ -180,9 +178,9 @@ func onFileRecv(t *gotox.Tox, friendNumber uint32, fileNumber uint32, kind gotox
 		}
 
 		publicKey, _ := t.FriendGetPublickey(friendNumber)
-		file, err := os.Create("example_" + hex.EncodeToString(publicKey) + ".png")
+		file, err := os.Create("example_" + publicKey.String() + ".png")
 		if err != nil {
-			fmt.Println("[ERROR] Error creating file", "example_"+hex.EncodeToString(publicKey)+".png")
+			fmt.Println("[ERROR] Error creating file", "example_"+publicKey.String()+".png")
 		}
 
 		// append the file to the map of active file transfers
@@ -280,32 +278,3 @@ func onFileRecvChunk(t *gotox.Tox, friendNumber uint32, fileNumber uint32, posit
 		t.FriendSendMessage(friendNumber, gotox.TOX_MESSAGE_TYPE_NORMAL, "Thanks!")
 	}
 }
-
-// loadData reads a file and returns its content as a byte array
-func loadData(filepath string) ([]byte, error) {
-	if len(filepath) == 0 {
-		return nil, errors.New("Empty path")
-	}
-
-	data, err := ioutil.ReadFile(filepath)
-	if err != nil {
-		return nil, err
-	}
-
-	return data, err
-}
-
-// saveData writes the savedata from toxcore to a file
-func saveData(t *gotox.Tox, filepath string) error {
-	if len(filepath) == 0 {
-		return errors.New("Empty path")
-	}
-
-	data, err := t.GetSavedata()
-	if err != nil {
-		return err
-	}
-
-	err = ioutil.WriteFile(filepath, data, 0644)
-	return err
-}
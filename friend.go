@@ -0,0 +1,187 @@
+package gotox
+
+import "time"
+
+/* Friend is a handle to a friend of a Tox instance. Unlike a plain friend
+ * number it remembers the public key of the friend, so it notices when the
+ * friend has been deleted and the friend number has been reused. Methods then
+ * return ErrFriendGone.
+ *
+ * A Friend obtained from the *Tox passed to a callback is only valid during
+ * that callback, like the *Tox itself. */
+type Friend struct {
+	tox       *Tox
+	number    uint32
+	publicKey PublicKey
+}
+
+/* FriendSnapshot holds the state of a friend at one point in time. */
+type FriendSnapshot struct {
+	Number        uint32
+	PublicKey     PublicKey
+	Name          string
+	StatusMessage string
+	Status        ToxUserStatus
+	Connection    ToxConnection
+	Typing        bool
+	LastOnline    time.Time
+}
+
+/* Friend returns the handle of the friend with the given friend number. */
+func (t *Tox) Friend(friendNumber uint32) (*Friend, error) {
+	publicKey, err := t.FriendGetPublickey(friendNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Friend{t, friendNumber, publicKey}, nil
+}
+
+/* FriendByKey returns the handle of the friend with the given public key. */
+func (t *Tox) FriendByKey(publicKey PublicKey) (*Friend, error) {
+	friendNumber, err := t.FriendByPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Friend{t, friendNumber, publicKey}, nil
+}
+
+/* do calls f with the lock held, after making sure the friend number still
+ * belongs to this friend. f must use the *Tox it is passed, which does not
+ * lock again. */
+func (f *Friend) do(fn func(t *Tox) error) error {
+	if err := f.tox.lock(); err != nil {
+		return err
+	}
+	defer f.tox.unlock()
+
+	t := f.tox.lockedTox
+	publicKey, err := t.FriendGetPublickey(f.number)
+	if err != nil || publicKey != f.publicKey {
+		return ErrFriendGone
+	}
+
+	return fn(t)
+}
+
+/* Number returns the friend number. */
+func (f *Friend) Number() uint32 {
+	return f.number
+}
+
+/* PublicKey returns the public key of the friend. */
+func (f *Friend) PublicKey() PublicKey {
+	return f.publicKey
+}
+
+/* Name returns the name of the friend. */
+func (f *Friend) Name() (name string, err error) {
+	err = f.do(func(t *Tox) error {
+		name, err = t.FriendGetName(f.number)
+		return err
+	})
+	return name, err
+}
+
+/* StatusMessage returns the status message of the friend. */
+func (f *Friend) StatusMessage() (message string, err error) {
+	err = f.do(func(t *Tox) error {
+		message, err = t.FriendGetStatusMessage(f.number)
+		return err
+	})
+	return message, err
+}
+
+/* Status returns the user status of the friend. */
+func (f *Friend) Status() (status ToxUserStatus, err error) {
+	err = f.do(func(t *Tox) error {
+		status, err = t.FriendGetStatus(f.number)
+		return err
+	})
+	return status, err
+}
+
+/* Connection returns the connection status of the friend. */
+func (f *Friend) Connection() (connection ToxConnection, err error) {
+	err = f.do(func(t *Tox) error {
+		connection, err = t.FriendGetConnectionStatus(f.number)
+		return err
+	})
+	return connection, err
+}
+
+/* Typing returns whether the friend is typing. */
+func (f *Friend) Typing() (typing bool, err error) {
+	err = f.do(func(t *Tox) error {
+		typing, err = t.FriendGetTyping(f.number)
+		return err
+	})
+	return typing, err
+}
+
+/* LastOnline returns the last time the friend was seen online, or the zero
+ * time.Time if they have never been online. */
+func (f *Friend) LastOnline() (last time.Time, err error) {
+	err = f.do(func(t *Tox) error {
+		last, err = t.FriendGetLastOnline(f.number)
+		return err
+	})
+	return last, err
+}
+
+/* SendMessage sends a message to the friend and returns the message ID. */
+func (f *Friend) SendMessage(messagetype ToxMessageType, message string) (messageID uint32, err error) {
+	err = f.do(func(t *Tox) error {
+		messageID, err = t.FriendSendMessage(f.number, messagetype, message)
+		return err
+	})
+	return messageID, err
+}
+
+/* SendFile starts a file transfer to the friend and returns the file number.
+ * See FileSend for the arguments. */
+func (f *Friend) SendFile(fileKind ToxFileKind, fileLength uint64, fileID []byte, fileName string) (fileNumber uint32, err error) {
+	err = f.do(func(t *Tox) error {
+		fileNumber, err = t.FileSend(f.number, fileKind, fileLength, fileID, fileName)
+		return err
+	})
+	return fileNumber, err
+}
+
+/* Delete removes the friend from the friend list. */
+func (f *Friend) Delete() error {
+	return f.do(func(t *Tox) error {
+		return t.FriendDelete(f.number)
+	})
+}
+
+/* Snapshot returns the state of the friend, read under a single lock. */
+func (f *Friend) Snapshot() (FriendSnapshot, error) {
+	s := FriendSnapshot{Number: f.number, PublicKey: f.publicKey}
+	err := f.do(func(t *Tox) error {
+		var err error
+		if s.Name, err = t.FriendGetName(f.number); err != nil {
+			return err
+		}
+		if s.StatusMessage, err = t.FriendGetStatusMessage(f.number); err != nil {
+			return err
+		}
+		if s.Status, err = t.FriendGetStatus(f.number); err != nil {
+			return err
+		}
+		if s.Connection, err = t.FriendGetConnectionStatus(f.number); err != nil {
+			return err
+		}
+		if s.Typing, err = t.FriendGetTyping(f.number); err != nil {
+			return err
+		}
+		s.LastOnline, err = t.FriendGetLastOnline(f.number)
+		return err
+	})
+	if err != nil {
+		return FriendSnapshot{}, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,71 @@
+package gotox
+
+import "encoding/json"
+import "os"
+import "sync"
+
+/* handlerList holds the handlers subscribed to the events of a Roster,
+ * NospamManager, etc. Like subscribers, the slice is copied on write. */
+type handlerList struct {
+	mtx      sync.Mutex
+	handlers []subscriber
+	nextID   uint64
+}
+
+/* add appends f and returns a func removing it again. */
+func (l *handlerList) add(f interface{}) func() {
+	l.mtx.Lock()
+	l.nextID++
+	id := l.nextID
+	handlers := make([]subscriber, len(l.handlers), len(l.handlers)+1)
+	copy(handlers, l.handlers)
+	l.handlers = append(handlers, subscriber{id, f})
+	l.mtx.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mtx.Lock()
+			defer l.mtx.Unlock()
+
+			handlers := make([]subscriber, 0, len(l.handlers))
+			for _, h := range l.handlers {
+				if h.id != id {
+					handlers = append(handlers, h)
+				}
+			}
+			l.handlers = handlers
+		})
+	}
+}
+
+func (l *handlerList) get() []subscriber {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.handlers
+}
+
+/* readJSONFile decodes the JSON file at path into v. A missing file is not an
+ * error, v is left unchanged. */
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+/* writeJSONFile writes v as JSON to path. The file is replaced atomically
+ * like by FileStorage, so a crash never leaves a partial file. */
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return (&FileStorage{Path: path}).Save(data)
+}
@@ -0,0 +1,88 @@
+package gotox
+
+/*
+#include <tox/tox.h>
+*/
+import "C"
+import "log"
+import "sync"
+import "unsafe"
+import "weak"
+
+/* Go pointers must not be kept by C code, so instead of passing the *Tox to
+ * tox_iterate we pass an integer handle as userdata. The hooks use the handle
+ * to look up the instance in this registry.
+ *
+ * The registry only holds weak pointers. The handlers stored in an instance
+ * often reference the *Tox returned by New, so a strong reference would keep
+ * every instance reachable, and an instance that is dropped without Kill
+ * could never be detected. */
+var registry = struct {
+	sync.RWMutex
+	instances map[uintptr]weak.Pointer[toxInstance]
+	next      uintptr
+}{instances: make(map[uintptr]weak.Pointer[toxInstance])}
+
+/* registerInstance adds t to the registry and returns its handle. */
+func registerInstance(t *toxInstance) uintptr {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.next++
+	registry.instances[registry.next] = weak.Make(t)
+	return registry.next
+}
+
+/* unregisterInstance removes the instance with the given handle from the
+ * registry. */
+func unregisterInstance(handle uintptr) {
+	registry.Lock()
+	delete(registry.instances, handle)
+	registry.Unlock()
+}
+
+/* lookupInstance returns the *Tox to pass to callbacks for the userdata given
+ * to a hook, or nil if the instance has been unregistered. */
+func lookupInstance(userdata unsafe.Pointer) *Tox {
+	registry.RLock()
+	t := registry.instances[uintptr(userdata)].Value()
+	registry.RUnlock()
+
+	if t == nil {
+		return nil
+	}
+	return t.callbackTox
+}
+
+/* instanceResources are released by Kill, or by the cleanup of an instance
+ * that is garbage collected without Kill. The cleanup is attached to the
+ * toxInstance, which every *Tox of the instance shares, so it only runs once
+ * none of them is reachable anymore. They must not reference the toxInstance,
+ * as the cleanup would never run otherwise. */
+type instanceResources struct {
+	tox       *C.Tox
+	cOptions  *C.struct_Tox_Options
+	handle    uintptr
+	logHandle uintptr
+
+	// Unlocks the Storage the instance was loaded from, if any
+	storageUnlock func() error
+}
+
+func (r *instanceResources) release() {
+	C.tox_options_free(r.cOptions)
+	C.tox_kill(r.tox)
+	unregisterInstance(r.handle)
+	unregisterLogSink(r.logHandle)
+
+	if r.storageUnlock != nil {
+		r.storageUnlock()
+	}
+}
+
+/* releaseLeaked warns about and releases an instance that was garbage
+ * collected without having been killed. */
+func releaseLeaked(r *instanceResources) {
+	log.Println("gotox: Tox instance was garbage collected without calling Kill()")
+	r.release()
+}
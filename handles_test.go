@@ -0,0 +1,61 @@
+package gotox
+
+import "sync"
+import "testing"
+
+/* testOptions returns options for an instance that only talks to instances on
+ * the local host. */
+func testOptions() *Options {
+	options := DefaultOptions()
+	options.IPv6Enabled = false
+	options.LocalDiscoveryDisabled = true
+	options.Logger = LoggerFunc(func(LogRecord) {})
+	return options
+}
+
+func TestNewKillConcurrent(t *testing.T) {
+	const goroutines = 16
+	const rounds = 5
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines*rounds)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				tox, err := New(testOptions())
+				if err != nil {
+					errs <- err
+					return
+				}
+				if err := tox.Iterate(); err != nil {
+					errs <- err
+				}
+				if err := tox.Kill(); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	registry.RLock()
+	instances := len(registry.instances)
+	registry.RUnlock()
+	if instances != 0 {
+		t.Errorf("%d instances left in the registry", instances)
+	}
+
+	logSinks.RLock()
+	sinks := len(logSinks.sinks)
+	logSinks.RUnlock()
+	if sinks != 0 {
+		t.Errorf("%d log sinks left in the registry", sinks)
+	}
+}
@@ -1,10 +1,14 @@
 #include <tox/tox.h>
 
 /* Convenient macro:
- * Creates the C function to directly register a given callback */
+ * Creates the C functions to directly register and unregister a given
+ * callback */
 #define CREATE_HOOK(x) \
 static void set_##x(Tox *tox) { \
   tox_##x(tox, hook_##x); \
+} \
+static void unset_##x(Tox *tox) { \
+  tox_##x(tox, NULL); \
 }
 
 // Headers for the exported GO functions in hooks.go
@@ -23,6 +27,7 @@ void hook_callback_file_recv(Tox*, uint32_t, uint32_t, uint32_t, uint64_t, const
 void hook_callback_file_recv_chunk(Tox*, uint32_t, uint32_t, uint64_t, const uint8_t*, size_t, void*);
 void hook_callback_friend_lossy_packet(Tox*, uint32_t, const uint8_t*, size_t, void*);
 void hook_callback_friend_lossless_packet(Tox*, uint32_t, const uint8_t*, size_t, void*);
+void hook_log_callback(Tox*, TOX_LOG_LEVEL, const char*, uint32_t, const char*, const char*, void*);
 
 CREATE_HOOK(callback_self_connection_status)
 CREATE_HOOK(callback_friend_name)
@@ -39,3 +44,9 @@ CREATE_HOOK(callback_file_recv)
 CREATE_HOOK(callback_file_recv_chunk)
 CREATE_HOOK(callback_friend_lossy_packet)
 CREATE_HOOK(callback_friend_lossless_packet)
+
+// The log callback is part of the Tox_Options, not of the Tox instance
+static void set_log_callback(struct Tox_Options *options, uintptr_t handle) {
+  tox_options_set_log_callback(options, hook_log_callback);
+  tox_options_set_log_user_data(options, (void *)handle);
+}
@@ -0,0 +1,128 @@
+package gotox
+
+import "encoding/hex"
+import "strings"
+import "unicode"
+
+/* PublicKey is the long term public key of a Tox profile. It is comparable
+ * and can be used as a map key. PublicKey implements encoding.TextMarshaler,
+ * so it is encoded as a hex string in JSON, also as a map key. */
+type PublicKey [TOX_PUBLIC_KEY_SIZE]byte
+
+/* SecretKey is the long term secret key of a Tox profile. String redacts the
+ * key, so it doesn't end up in logs by accident. MarshalText does not redact. */
+type SecretKey [TOX_SECRET_KEY_SIZE]byte
+
+/* decodeHex decodes the hex string s into dst. Upper and lower case are
+ * accepted and whitespace is ignored. It fails unless s decodes to exactly
+ * len(dst) bytes. */
+func decodeHex(dst []byte, s string) bool {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+
+	if hex.DecodedLen(len(s)) != len(dst) || len(s)%2 != 0 {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+/* PublicKeyFromBytes converts a []byte of length TOX_PUBLIC_KEY_SIZE to a
+ * PublicKey. */
+func PublicKeyFromBytes(b []byte) (PublicKey, error) {
+	var key PublicKey
+	if len(b) != len(key) {
+		return key, ErrArgs
+	}
+	copy(key[:], b)
+	return key, nil
+}
+
+/* ParsePublicKey parses the hexadecimal form of a public key. */
+func ParsePublicKey(s string) (PublicKey, error) {
+	var key PublicKey
+	if !decodeHex(key[:], s) {
+		return key, ErrKeyInvalid
+	}
+	return key, nil
+}
+
+/* Bytes returns the key as a byte slice. */
+func (key PublicKey) Bytes() []byte {
+	return append([]byte(nil), key[:]...)
+}
+
+/* String returns the key as upper case hex characters. */
+func (key PublicKey) String() string {
+	return strings.ToUpper(hex.EncodeToString(key[:]))
+}
+
+/* MarshalText implements encoding.TextMarshaler. */
+func (key PublicKey) MarshalText() ([]byte, error) {
+	return []byte(key.String()), nil
+}
+
+/* UnmarshalText implements encoding.TextUnmarshaler using ParsePublicKey. */
+func (key *PublicKey) UnmarshalText(text []byte) error {
+	parsed, err := ParsePublicKey(string(text))
+	if err != nil {
+		return err
+	}
+	*key = parsed
+	return nil
+}
+
+/* SecretKeyFromBytes converts a []byte of length TOX_SECRET_KEY_SIZE to a
+ * SecretKey. */
+func SecretKeyFromBytes(b []byte) (SecretKey, error) {
+	var key SecretKey
+	if len(b) != len(key) {
+		return key, ErrArgs
+	}
+	copy(key[:], b)
+	return key, nil
+}
+
+/* ParseSecretKey parses the hexadecimal form of a secret key. */
+func ParseSecretKey(s string) (SecretKey, error) {
+	var key SecretKey
+	if !decodeHex(key[:], s) {
+		return key, ErrKeyInvalid
+	}
+	return key, nil
+}
+
+/* Bytes returns the key as a byte slice. */
+func (key SecretKey) Bytes() []byte {
+	return append([]byte(nil), key[:]...)
+}
+
+/* String returns a placeholder instead of the key. */
+func (key SecretKey) String() string {
+	return "SecretKey(REDACTED)"
+}
+
+/* GoString redacts the key for the %#v verb. */
+func (key SecretKey) GoString() string {
+	return key.String()
+}
+
+/* MarshalText implements encoding.TextMarshaler. The key is returned in upper
+ * case hex characters, it is not redacted. */
+func (key SecretKey) MarshalText() ([]byte, error) {
+	return []byte(strings.ToUpper(hex.EncodeToString(key[:]))), nil
+}
+
+/* UnmarshalText implements encoding.TextUnmarshaler using ParseSecretKey. */
+func (key *SecretKey) UnmarshalText(text []byte) error {
+	parsed, err := ParseSecretKey(string(text))
+	if err != nil {
+		return err
+	}
+	*key = parsed
+	return nil
+}
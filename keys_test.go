@@ -0,0 +1,114 @@
+package gotox
+
+import "encoding/hex"
+import "encoding/json"
+import "fmt"
+import "strings"
+import "testing"
+
+const testPublicKey = "F404ABAA1C99A9D37D61AB54898F56793E1DEF8BD46B1038B9D822E8460FAB67"
+
+func TestParsePublicKey(t *testing.T) {
+	for _, s := range []string{testPublicKey, strings.ToLower(testPublicKey), " " + testPublicKey + "\n"} {
+		key, err := ParsePublicKey(s)
+		if err != nil {
+			t.Errorf("ParsePublicKey(%q): %v", s, err)
+		} else if key.String() != testPublicKey {
+			t.Errorf("ParsePublicKey(%q): got %v", s, key)
+		}
+	}
+
+	for _, s := range []string{"", testPublicKey[:62], testPublicKey + "00", testPublicKey[:62] + "XY"} {
+		if _, err := ParsePublicKey(s); err != ErrKeyInvalid {
+			t.Errorf("ParsePublicKey(%q): got %v, want ErrKeyInvalid", s, err)
+		}
+	}
+}
+
+func TestParseSecretKey(t *testing.T) {
+	key, err := ParseSecretKey(strings.ToLower(testPublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := key.MarshalText(); string(text) != testPublicKey {
+		t.Errorf("MarshalText: got %s", text)
+	}
+
+	if _, err := ParseSecretKey(testPublicKey[:62]); err != ErrKeyInvalid {
+		t.Errorf("ParseSecretKey with a short key: got %v, want ErrKeyInvalid", err)
+	}
+}
+
+func TestPublicKeyJSON(t *testing.T) {
+	key, err := ParsePublicKey(testPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(map[PublicKey]PublicKey{key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"` + testPublicKey + `":"` + testPublicKey + `"}`
+	if string(data) != want {
+		t.Errorf("Marshal: got %s, want %s", data, want)
+	}
+
+	var decoded map[PublicKey]PublicKey
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 1 || decoded[key] != key {
+		t.Errorf("Unmarshal: got %v", decoded)
+	}
+}
+
+func TestSecretKeyJSON(t *testing.T) {
+	key, err := ParseSecretKey(testPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(map[SecretKey]SecretKey{key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[SecretKey]SecretKey
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 1 || decoded[key] != key {
+		t.Errorf("Unmarshal: got %v", decoded)
+	}
+}
+
+func TestSecretKeyRedacted(t *testing.T) {
+	key, err := ParseSecretKey(testPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forms := []string{
+		testPublicKey,
+		strings.ToLower(testPublicKey),
+		fmt.Sprint([TOX_SECRET_KEY_SIZE]byte(key)),
+		fmt.Sprintf("%#v", [TOX_SECRET_KEY_SIZE]byte(key)),
+		// a few bytes suffice to recognize a dump of the key
+		hex.EncodeToString(key[:4]),
+		fmt.Sprint(key[:4])[1:10],
+	}
+
+	wrapped := struct{ Key SecretKey }{key}
+	for _, format := range []string{"%v", "%s", "%x", "%X", "%#v", "%+v"} {
+		for _, value := range []interface{}{key, &key, wrapped} {
+			out := fmt.Sprintf(format, value)
+			for _, form := range forms {
+				if strings.Contains(strings.ToLower(out), strings.ToLower(form)) {
+					t.Errorf("%s of %T prints the key: %s", format, value, out)
+					break
+				}
+			}
+		}
+	}
+}
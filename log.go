@@ -0,0 +1,107 @@
+package gotox
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"unsafe"
+	"weak"
+)
+
+/* LogRecord is a log message emitted by toxcore. */
+type LogRecord struct {
+	Level    ToxLogLevel
+	File     string
+	Line     uint32
+	Function string
+	Message  string
+}
+
+/* Logger receives the log messages of toxcore. Log may be called from any
+ * goroutine that calls into Tox, and also during New. */
+type Logger interface {
+	Log(record LogRecord)
+}
+
+/* LoggerFunc adapts an ordinary function to the Logger interface. */
+type LoggerFunc func(record LogRecord)
+
+func (f LoggerFunc) Log(record LogRecord) {
+	f(record)
+}
+
+/* NewSlogLogger returns a Logger writing the toxcore log messages to l. */
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Log(record LogRecord) {
+	var level slog.Level
+	switch record.Level {
+	case TOX_LOG_LEVEL_TRACE:
+		level = slog.LevelDebug - 4
+	case TOX_LOG_LEVEL_DEBUG:
+		level = slog.LevelDebug
+	case TOX_LOG_LEVEL_INFO:
+		level = slog.LevelInfo
+	case TOX_LOG_LEVEL_WARNING:
+		level = slog.LevelWarn
+	default:
+		level = slog.LevelError
+	}
+
+	s.l.Log(context.Background(), level, record.Message,
+		slog.String("file", record.File),
+		slog.Uint64("line", uint64(record.Line)),
+		slog.String("func", record.Function))
+}
+
+/* logSink is registered for the lifetime of an instance with a Logger. Like
+ * the instances themselves, sinks are passed to toxcore as integer handles,
+ * and the registry only holds weak pointers; the instance keeps its sink
+ * alive. */
+type logSink struct {
+	logger   Logger
+	minLevel ToxLogLevel
+}
+
+var logSinks = struct {
+	sync.RWMutex
+	sinks map[uintptr]weak.Pointer[logSink]
+	next  uintptr
+}{sinks: make(map[uintptr]weak.Pointer[logSink])}
+
+func registerLogSink(sink *logSink) uintptr {
+	logSinks.Lock()
+	defer logSinks.Unlock()
+
+	logSinks.next++
+	logSinks.sinks[logSinks.next] = weak.Make(sink)
+	return logSinks.next
+}
+
+func unregisterLogSink(handle uintptr) {
+	logSinks.Lock()
+	delete(logSinks.sinks, handle)
+	logSinks.Unlock()
+}
+
+func lookupLogSink(userdata unsafe.Pointer) *logSink {
+	logSinks.RLock()
+	defer logSinks.RUnlock()
+
+	return logSinks.sinks[uintptr(userdata)].Value()
+}
+
+/* log passes record to the Logger, ignoring a panic in the Logger so that it
+ * cannot unwind through toxcore. */
+func (s *logSink) log(record LogRecord) {
+	defer func() {
+		recover()
+	}()
+	s.logger.Log(record)
+}
@@ -0,0 +1,90 @@
+package gotox
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+/* Run calls Iterate at the interval recommended by toxcore until ctx is
+ * cancelled. It also executes the functions scheduled with Do, so all access to
+ * toxcore can be kept on the goroutine that calls Run.
+ * Run returns ctx.Err() when the context is cancelled, also while it is blocked
+ * on a full event channel, or the error returned by Iterate or
+ * IterationInterval. ErrEventOverflow does not stop Run, the events are
+ * dropped as configured by EVENT_OVERFLOW_ERROR. */
+func (t *Tox) Run(ctx context.Context) error {
+	if err := t.checkIterate(); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.doWake:
+			t.runScheduled()
+		case <-timer.C:
+			if err := t.iterate(ctx); err != nil && !t.panicHandled(err) && !errors.Is(err, ErrEventOverflow) {
+				return err
+			}
+			t.runScheduled()
+
+			interval, err := t.IterationInterval()
+			if err != nil {
+				return err
+			}
+			timer.Reset(time.Duration(interval) * time.Millisecond)
+		}
+	}
+}
+
+/* Do schedules f to be called on the goroutine running Run. Functions are
+ * executed in the order they were scheduled. Do never blocks, so it may also be
+ * called from within callbacks. If Run is not running, f is executed once it
+ * is started. */
+func (t *Tox) Do(f func(t *Tox)) {
+	if f == nil {
+		return
+	}
+
+	t.doMtx.Lock()
+	t.doQueue = append(t.doQueue, f)
+	t.doMtx.Unlock()
+
+	select {
+	case t.doWake <- struct{}{}:
+	default:
+	}
+}
+
+/* runScheduled executes all functions queued by Do. */
+func (t *Tox) runScheduled() {
+	t.doMtx.Lock()
+	queue := t.doQueue
+	t.doQueue = nil
+	t.doMtx.Unlock()
+
+	for _, f := range queue {
+		f(t)
+	}
+}
+
+/* panicHandled returns true if err is a recovered panic that has already been
+ * passed to the PanicHandler. */
+func (t *Tox) panicHandled(err error) bool {
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		return false
+	}
+
+	if t.lock() != nil {
+		return false
+	}
+	defer t.unlock()
+
+	return t.panicHandler != nil
+}
@@ -0,0 +1,137 @@
+package gotox
+
+import "context"
+import "fmt"
+import "sync"
+import "testing"
+import "time"
+
+/* newTestTox creates an instance that is killed at the end of the test. */
+func newTestTox(t *testing.T) *Tox {
+	tox, err := New(testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tox.Kill() })
+	return tox
+}
+
+/* connect bootstraps a and b off each other over the loopback interface. */
+func connect(t *testing.T, a *Tox, b *Tox) {
+	for _, pair := range [][2]*Tox{{a, b}, {b, a}} {
+		port, err := pair[1].SelfGetUDPPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dhtID, err := pair[1].SelfGetDhtId()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := pair[0].Bootstrap("127.0.0.1", port, dhtID); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+/* Calls from several goroutines race with the callbacks running in Run. Run
+ * with -race. */
+func TestConcurrentCallsDuringRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("connects two instances")
+	}
+
+	alice := newTestTox(t)
+	bob := newTestTox(t)
+	connect(t, alice, bob)
+
+	var kept *Tox
+	var accept sync.Once
+	bob.OnFriendRequest(func(tox *Tox, publicKey PublicKey, message string) {
+		accept.Do(func() {
+			// call back into the *Tox passed to the callback
+			if _, err := tox.FriendAddNorequest(publicKey); err != nil {
+				t.Error(err)
+			}
+			if err := tox.Iterate(); err != ErrIterateInCallback {
+				t.Errorf("Iterate in callback: got %v, want ErrIterateInCallback", err)
+			}
+			kept = tox
+		})
+	})
+
+	connected := make(chan struct{})
+	var online sync.Once
+	var unsubscribe func()
+	unsubscribe = alice.OnFriendConnectionStatusChanges(func(tox *Tox, friendNumber uint32, status ToxConnection) {
+		if status == TOX_CONNECTION_NONE {
+			return
+		}
+		if _, err := tox.SelfGetName(); err != nil {
+			t.Error(err)
+		}
+		// unsubscribing from a handler must not deadlock
+		unsubscribe()
+		online.Do(func() { close(connected) })
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, tox := range []*Tox{alice, bob} {
+		wg.Add(1)
+		go func(tox *Tox) {
+			defer wg.Done()
+			if err := tox.Run(ctx); err != context.Canceled {
+				t.Errorf("Run: %v", err)
+			}
+		}(tox)
+	}
+
+	address, err := bob.SelfGetAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	friendNumber, err := alice.FriendAdd(address, "Hello Bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-time.After(time.Millisecond):
+				}
+
+				if err := alice.SelfSetName(fmt.Sprintf("Alice %d", i)); err != nil {
+					t.Error(err)
+					return
+				}
+				// fails until the friend is online
+				alice.FriendSendMessage(friendNumber, TOX_MESSAGE_TYPE_NORMAL, "Hello")
+			}
+		}(i)
+	}
+
+	select {
+	case <-connected:
+	case <-ctx.Done():
+		t.Error("alice and bob did not connect")
+	}
+	close(stop)
+	cancel()
+	wg.Wait()
+
+	if kept == nil {
+		t.Fatal("bob received no friend request")
+	}
+	if _, err := kept.SelfGetName(); err != ErrNotInCallback {
+		t.Errorf("callback *Tox after the callback: got %v, want ErrNotInCallback", err)
+	}
+}
@@ -14,14 +14,53 @@ package gotox
 //#cgo LDFLAGS: -ltoxcore
 //#include <tox/tox.h>
 import "C"
+import "runtime"
 import "sync"
+import "sync/atomic"
 
-/* Tox is the main struct. */
+/* Tox is the main struct.
+ *
+ * All methods are safe for concurrent use. Calls into toxcore are serialized
+ * by a lock which Iterate holds while the callbacks run. Callbacks must call
+ * back into Tox through the *Tox they are passed, which doesn't lock again;
+ * calling through any other *Tox of the same instance, or a method of a
+ * Roster, NospamManager, RequestTracker, FriendRequestPolicy or ProfileStore
+ * that calls into toxcore, deadlocks. Use Do to run such calls after Iterate
+ * returns. The *Tox passed to callbacks is only valid until the callback
+ * returns and only on the goroutine running it; afterwards its methods return
+ * ErrNotInCallback. */
 type Tox struct {
+	*toxInstance
+
+	// Set for the *Tox passed to callbacks, see callbackTox.
+	inCallback bool
+
+	// Set for lockedTox: the caller already holds the lock.
+	lockHeld bool
+}
+
+type toxInstance struct {
 	cOptions *C.struct_Tox_Options
 	tox      *C.Tox
 	mtx      sync.Mutex
 
+	// Handle passed to toxcore as userdata, see handles.go
+	handle uintptr
+
+	// The log sink, if a Logger was given in the Options. The log sink
+	// registry only references it weakly.
+	logSink *logSink
+
+	// Released by Kill, or by the cleanup if the instance is garbage
+	// collected without Kill, see handles.go
+	resources *instanceResources
+	cleanup   runtime.Cleanup
+
+	// Functions scheduled by Do, executed by Run
+	doMtx   sync.Mutex
+	doQueue []func(*Tox)
+	doWake  chan struct{}
+
 	// Callbacks
 	onSelfConnectionStatusChanges   OnSelfConnectionStatusChanges
 	onFriendNameChanges             OnFriendNameChanges
@@ -38,6 +77,94 @@ type Tox struct {
 	onFileRecvChunk                 OnFileRecvChunk
 	onFriendLossyPacket             OnFriendLossyPacket
 	onFriendLosslessPacket          OnFriendLosslessPacket
+
+	// Handlers registered with the On* functions
+	subscribers subscribers
+
+	// Handlers notified by stateChanged, see ProfileStore
+	stateHandlers handlerList
+
+	// Storage given in the Options. It is unlocked on Kill.
+	storage Storage
+
+	// Handler for panics recovered during Iterate and the first of them
+	panicHandler PanicHandler
+	panicErr     *PanicError
+
+	// Event channel returned by Events and the events queued during Iterate
+	events        *eventStream
+	pendingEvents []Event
+
+	// The *Tox passed to callbacks, see inCallback
+	callbackTox *Tox
+
+	// The *Tox used internally while the lock is held, see lockHeld. It must
+	// not be passed on to users.
+	lockedTox *Tox
+
+	// Set while Iterate runs the callbacks, so callbackTox may be used
+	dispatching atomic.Bool
+
+	// Set when an unsubscribe couldn't update the C hooks while the callbacks
+	// ran; Iterate updates them before it unlocks
+	hooksStale atomic.Bool
+
+	// Set by Kill; killPending defers a Kill called from a callback until
+	// Iterate returns.
+	killed      bool
+	killPending bool
+}
+
+/* lock acquires the lock serializing all calls into toxcore, unless it is
+ * already held by the caller. It returns ErrKilled or ErrToxInit, without
+ * holding the lock, if the instance can't be used. */
+func (t *Tox) lock() error {
+	if t.toxInstance == nil {
+		return ErrToxInit
+	}
+
+	if t.inCallback {
+		if !t.dispatching.Load() {
+			return ErrNotInCallback
+		}
+	} else if !t.lockHeld {
+		t.mtx.Lock()
+	}
+
+	if t.tox == nil {
+		t.unlock()
+		if t.killed {
+			return ErrKilled
+		}
+		return ErrToxInit
+	}
+
+	return nil
+}
+
+func (t *Tox) unlock() {
+	if !t.inCallback && !t.lockHeld {
+		t.mtx.Unlock()
+	}
+}
+
+/* ownerTox returns a *Tox of the same instance that locks like the one
+ * returned by New. Unlike t, which may be the *Tox passed to a callback, it can
+ * be kept after the callback has returned. */
+func (t *Tox) ownerTox() *Tox {
+	return &Tox{toxInstance: t.toxInstance}
+}
+
+/* withLock calls f with the Tox lock held, passing the *Tox to use while it
+ * is held. Helpers with a mutex of their own take it in f: the Tox lock is
+ * always taken first, as it is already held when the callbacks run. */
+func (t *Tox) withLock(f func(t *Tox) error) error {
+	if err := t.lock(); err != nil {
+		return err
+	}
+	defer t.unlock()
+
+	return f(t.lockedTox)
 }
 
 type Options struct {
@@ -54,6 +181,23 @@ type Options struct {
 	 */
 	UDPEnabled bool
 
+	/* Disable local network peer discovery.
+	 *
+	 * By default Tox looks for peers on the local network by sending broadcast
+	 * packets. Servers usually want to turn this off.
+	 */
+	LocalDiscoveryDisabled bool
+
+	/* Disable UDP hole punching. */
+	HolePunchingDisabled bool
+
+	/* Do not announce this instance in the DHT.
+	 *
+	 * Friends can then only reach us if we connect to them first. Requires
+	 * toxcore 0.2.16 or later.
+	 */
+	DHTAnnouncementsDisabled bool
+
 	/* The type of the proxy (PROXY_TYPE_NONE, PROXY_TYPE_HTTP or PROXY_TYPE_SOCKS5). */
 	ProxyType ToxProxyType
 
@@ -77,4 +221,26 @@ type Options struct {
 
 	/* The savedata. */
 	SaveData []byte
+
+	/* Let toxcore make its own calls thread-safe. gotox already serializes all
+	 * calls, so this is rarely needed. Requires toxcore 0.2.11 or later. */
+	ExperimentalThreadSafety bool
+
+	/* Save and load group chats in the savedata. Requires toxcore 0.2.19 or
+	 * later. */
+	ExperimentalGroupsPersistence bool
+
+	/* Do not resolve host names, e.g. of bootstrap nodes or the proxy. Requires
+	 * toxcore 0.2.20 or later. */
+	ExperimentalDisableDNS bool
+
+	/* Load the savedata from this Storage instead of SaveData. The storage
+	 * stays locked until Kill. */
+	Storage Storage
+
+	/* Receives the log messages of toxcore. If nil, they are discarded. */
+	Logger Logger
+
+	/* The lowest level passed to Logger. Less severe messages are discarded. */
+	LogLevel ToxLogLevel
 }
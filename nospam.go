@@ -0,0 +1,366 @@
+package gotox
+
+import "crypto/rand"
+import "encoding/binary"
+import "errors"
+import "sync"
+import "time"
+
+/* NospamRecord is an entry of the nospam history of a NospamManager. */
+type NospamRecord struct {
+	Nospam uint32
+	// Set for nospams issued by Invite
+	Invite bool
+	// Set for the regular nospam chosen by Rotate while an invite is
+	// outstanding. It is activated once the invite has been used.
+	Pending   bool
+	Activated time.Time
+	// Zero while the nospam is in use
+	Retired time.Time
+}
+
+/* NospamManagerOptions configures a NospamManager. */
+type NospamManagerOptions struct {
+	/* Rotate the nospam this often. If 0, the nospam is only rotated by
+	 * Rotate. */
+	Interval time.Duration
+
+	/* The history is loaded from and saved to this file as JSON. If empty, the
+	 * history is only kept in memory. */
+	HistoryFile string
+
+	/* Keep at most this many history records. If 0, all records are kept. */
+	MaxHistory int
+}
+
+/* This event is triggered when a NospamManager changes the address. */
+type OnAddressChange func(tox *Tox, previous ToxID, current ToxID)
+
+/* NospamManager rotates the nospam of a Tox instance, which changes its
+ * address and invalidates the old one for friend requests.
+ *
+ * The manager also issues single-use invite addresses. Tox only accepts
+ * friend requests for one nospam at a time, so Invite replaces the regular
+ * address (and any invite issued before) until the first friend request
+ * arrives. Then the regular address is restored.
+ *
+ * The nospam is part of the savedata, save the profile after it changed. */
+type NospamManager struct {
+	tox     *Tox
+	options NospamManagerOptions
+
+	mtx       sync.Mutex
+	publicKey PublicKey
+	history   []NospamRecord
+	regular   uint32
+	invite    bool
+	timer     *time.Timer
+	closed    bool
+
+	unsubscribe func()
+
+	handlers handlerList
+}
+
+/* NewNospamManager creates a NospamManager for t. If the history file records
+ * an outstanding invite for the current nospam, the invite stays valid. */
+func NewNospamManager(t *Tox, options *NospamManagerOptions) (*NospamManager, error) {
+	m := &NospamManager{tox: t}
+	if options != nil {
+		m.options = *options
+	}
+
+	history, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.tox.withLock(func(t *Tox) error {
+		m.mtx.Lock()
+		defer m.mtx.Unlock()
+
+		var err error
+		if m.publicKey, err = t.SelfGetPublicKey(); err != nil {
+			return err
+		}
+		nospam, err := t.SelfGetNospam()
+		if err != nil {
+			return err
+		}
+
+		m.history = history
+		if last := activeRecord(history); last >= 0 && history[last].Retired.IsZero() && history[last].Nospam == nospam {
+			m.regular = nospam
+			if history[last].Invite {
+				m.invite = true
+				if m.regular, err = regularNospam(history); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		m.regular = nospam
+		m.record(nospam, false)
+		return m.persist()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.unsubscribe, err = t.Subscribe(OnFriendRequest(func(t *Tox, publickey PublicKey, message string) {
+		m.inviteUsed(t)
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	if m.options.Interval > 0 {
+		m.timer = time.AfterFunc(m.options.Interval, func() { m.Rotate() })
+	}
+
+	return m, nil
+}
+
+/* Close stops the scheduled rotation and invite tracking. */
+func (m *NospamManager) Close() {
+	m.mtx.Lock()
+	m.closed = true
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.mtx.Unlock()
+
+	m.unsubscribe()
+}
+
+/* Address returns the address currently accepting friend requests. This is
+ * the outstanding invite, if there is one. */
+func (m *NospamManager) Address() ToxID {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return NewToxID(m.publicKey, m.current())
+}
+
+/* RegularAddress returns the address that is valid whenever no invite is
+ * outstanding. */
+func (m *NospamManager) RegularAddress() ToxID {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return NewToxID(m.publicKey, m.regular)
+}
+
+/* History returns a copy of the nospam history, oldest first. */
+func (m *NospamManager) History() []NospamRecord {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return append([]NospamRecord(nil), m.history...)
+}
+
+/* Rotate replaces the regular address with a new one. If an invite is
+ * outstanding, it stays valid and the new regular address is used once the
+ * invite has been used. It must not be called from a callback; use Do
+ * instead. */
+func (m *NospamManager) Rotate() error {
+	var previous, current ToxID
+
+	err := m.tox.withLock(func(t *Tox) error {
+		m.mtx.Lock()
+		defer m.mtx.Unlock()
+
+		nospam, err := randomNospam()
+		if err != nil {
+			return err
+		}
+
+		if m.invite {
+			// keep the new regular nospam across restarts
+			m.regular = nospam
+			m.recordPending(nospam)
+			return m.persist()
+		}
+
+		previous, current, err = m.set(t, nospam, false)
+		if current != previous {
+			// in use, even if the history couldn't be written
+			m.regular = nospam
+		}
+		return err
+	})
+
+	m.mtx.Lock()
+	if m.timer != nil && !m.closed && !errors.Is(err, ErrKilled) {
+		m.timer.Reset(m.options.Interval)
+	}
+	m.mtx.Unlock()
+
+	if previous != current {
+		m.emit(m.tox, previous, current)
+	}
+	return err
+}
+
+/* Invite issues a single-use address. It accepts friend requests until the
+ * first one arrives. It must not be called from a callback; use Do instead. */
+func (m *NospamManager) Invite() (ToxID, error) {
+	var previous, current ToxID
+
+	err := m.tox.withLock(func(t *Tox) error {
+		m.mtx.Lock()
+		defer m.mtx.Unlock()
+
+		nospam, err := randomNospam()
+		if err != nil {
+			return err
+		}
+
+		m.invite = true
+		previous, current, err = m.set(t, nospam, true)
+		return err
+	})
+	if previous != current {
+		m.emit(m.tox, previous, current)
+	}
+	if err != nil {
+		return ToxID{}, err
+	}
+
+	return current, nil
+}
+
+/* OnChange subscribes f to address changes. Subscribing nil has no effect. */
+func (m *NospamManager) OnChange(f OnAddressChange) (unsubscribe func()) {
+	if f == nil {
+		return func() {}
+	}
+	return m.handlers.add(f)
+}
+
+/* inviteUsed restores the regular address after a friend request arrived for
+ * an invite. t is the *Tox passed to the callback. */
+func (m *NospamManager) inviteUsed(t *Tox) {
+	m.mtx.Lock()
+	if !m.invite || m.closed {
+		m.mtx.Unlock()
+		return
+	}
+
+	m.invite = false
+	previous, current, _ := m.set(t, m.regular, false)
+	m.mtx.Unlock()
+
+	if previous != current {
+		m.emit(t, previous, current)
+	}
+}
+
+/* current returns the nospam in use. m.mtx must be held. */
+func (m *NospamManager) current() uint32 {
+	return m.history[activeRecord(m.history)].Nospam
+}
+
+/* set changes the nospam and records it. Both the Tox lock and m.mtx must be
+ * held. */
+func (m *NospamManager) set(t *Tox, nospam uint32, invite bool) (ToxID, ToxID, error) {
+	previous := NewToxID(m.publicKey, m.current())
+	if err := t.SelfSetNospam(nospam); err != nil {
+		return previous, previous, err
+	}
+
+	m.record(nospam, invite)
+	return previous, NewToxID(m.publicKey, nospam), m.persist()
+}
+
+/* record retires the current history record, drops a pending record and
+ * appends a new one. */
+func (m *NospamManager) record(nospam uint32, invite bool) {
+	now := time.Now()
+	m.dropPending()
+	if len(m.history) > 0 {
+		m.history[len(m.history)-1].Retired = now
+	}
+	m.appendRecord(NospamRecord{Nospam: nospam, Invite: invite, Activated: now})
+}
+
+/* recordPending replaces the pending record with one for nospam. */
+func (m *NospamManager) recordPending(nospam uint32) {
+	m.dropPending()
+	m.appendRecord(NospamRecord{Nospam: nospam, Pending: true})
+}
+
+/* dropPending removes the pending record, which is always the last one. */
+func (m *NospamManager) dropPending() {
+	if last := len(m.history) - 1; last >= 0 && m.history[last].Pending {
+		m.history = m.history[:last]
+	}
+}
+
+func (m *NospamManager) appendRecord(record NospamRecord) {
+	m.history = append(m.history, record)
+
+	// a pending record doesn't count, the record in use must be kept
+	limit := m.options.MaxHistory
+	if record.Pending {
+		limit++
+	}
+	if m.options.MaxHistory > 0 && len(m.history) > limit {
+		m.history = append([]NospamRecord(nil), m.history[len(m.history)-limit:]...)
+	}
+}
+
+func (m *NospamManager) load() ([]NospamRecord, error) {
+	var history []NospamRecord
+	if m.options.HistoryFile == "" {
+		return history, nil
+	}
+
+	err := readJSONFile(m.options.HistoryFile, &history)
+	return history, err
+}
+
+/* persist writes the history file. m.mtx must be held. */
+func (m *NospamManager) persist() error {
+	if m.options.HistoryFile == "" {
+		return nil
+	}
+
+	return writeJSONFile(m.options.HistoryFile, m.history)
+}
+
+func (m *NospamManager) emit(t *Tox, previous ToxID, current ToxID) {
+	for _, h := range m.handlers.get() {
+		h.f.(OnAddressChange)(t, previous, current)
+	}
+}
+
+/* activeRecord returns the index of the record of the nospam in use, which is
+ * the last one unless it is pending, or -1 if there is none. */
+func activeRecord(history []NospamRecord) int {
+	last := len(history) - 1
+	if last >= 0 && history[last].Pending {
+		last--
+	}
+	return last
+}
+
+/* regularNospam returns the last regular nospam in history, including a
+ * pending one, or a new one if there is none. */
+func regularNospam(history []NospamRecord) (uint32, error) {
+	for i := len(history) - 1; i >= 0; i-- {
+		if !history[i].Invite {
+			return history[i].Nospam, nil
+		}
+	}
+	return randomNospam()
+}
+
+func randomNospam() (uint32, error) {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b[:]), nil
+}
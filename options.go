@@ -0,0 +1,234 @@
+package gotox
+
+/*
+#include <tox/tox.h>
+#include <stdlib.h>
+#include <string.h>
+
+#define GOTOX_TOX_VERSION_AT_LEAST(major, minor, patch) \
+  (TOX_VERSION_MAJOR > (major) || (TOX_VERSION_MAJOR == (major) && \
+    (TOX_VERSION_MINOR > (minor) || (TOX_VERSION_MINOR == (minor) && TOX_VERSION_PATCH >= (patch)))))
+
+// The version of the library loaded at run time, which may be older than the
+// headers.
+static bool tox_library_at_least(uint32_t major, uint32_t minor, uint32_t patch) {
+  uint32_t lib_major = tox_version_major();
+  uint32_t lib_minor = tox_version_minor();
+  uint32_t lib_patch = tox_version_patch();
+  return lib_major > major || (lib_major == major &&
+    (lib_minor > minor || (lib_minor == minor && lib_patch >= patch)));
+}
+
+// Options added after toxcore 0.2.0 are set through these wrappers, which
+// return false if the toxcore headers we are built against or the library
+// loaded at run time lack the option.
+static bool options_set_experimental_thread_safety(struct Tox_Options *options, bool value) {
+#if GOTOX_TOX_VERSION_AT_LEAST(0, 2, 11)
+  if (!tox_library_at_least(0, 2, 11)) {
+    return false;
+  }
+  tox_options_set_experimental_thread_safety(options, value);
+  return true;
+#else
+  return false;
+#endif
+}
+
+static bool options_set_dht_announcements_enabled(struct Tox_Options *options, bool value) {
+#if GOTOX_TOX_VERSION_AT_LEAST(0, 2, 16)
+  if (!tox_library_at_least(0, 2, 16)) {
+    return false;
+  }
+  tox_options_set_dht_announcements_enabled(options, value);
+  return true;
+#else
+  return false;
+#endif
+}
+
+static bool options_set_experimental_groups_persistence(struct Tox_Options *options, bool value) {
+#if GOTOX_TOX_VERSION_AT_LEAST(0, 2, 19)
+  if (!tox_library_at_least(0, 2, 19)) {
+    return false;
+  }
+  tox_options_set_experimental_groups_persistence(options, value);
+  return true;
+#else
+  return false;
+#endif
+}
+
+static bool options_set_experimental_disable_dns(struct Tox_Options *options, bool value) {
+#if GOTOX_TOX_VERSION_AT_LEAST(0, 2, 20)
+  if (!tox_library_at_least(0, 2, 20)) {
+    return false;
+  }
+  tox_options_set_experimental_disable_dns(options, value);
+  return true;
+#else
+  return false;
+#endif
+}
+*/
+import "C"
+import "unsafe"
+
+/* OptionsError reports which field of Options caused an error. */
+type OptionsError struct {
+	Field string
+	Err   error
+}
+
+func (e *OptionsError) Error() string {
+	return "Options." + e.Field + ": " + e.Err.Error()
+}
+
+func (e *OptionsError) Unwrap() error {
+	return e.Err
+}
+
+/* DefaultOptions returns the options toxcore uses when New is called with nil
+ * options.
+ */
+func DefaultOptions() *Options {
+	return &Options{
+		IPv6Enabled:  true,
+		UDPEnabled:   true,
+		ProxyType:    TOX_PROXY_TYPE_NONE,
+		StartPort:    0,
+		EndPort:      0,
+		TcpPort:      0,
+		SaveDataType: TOX_SAVEDATA_TYPE_NONE,
+		LogLevel:     TOX_LOG_LEVEL_TRACE,
+	}
+}
+
+/* Validate checks options for invalid or contradictory settings. The returned
+ * error is an *OptionsError naming the offending field. New calls Validate
+ * before creating the Tox instance.
+ */
+func (options *Options) Validate() error {
+	if options.StartPort != 0 && options.EndPort != 0 && options.StartPort > options.EndPort {
+		return &OptionsError{"StartPort", ErrOptionPortRange}
+	}
+
+	switch options.ProxyType {
+	case TOX_PROXY_TYPE_NONE:
+	case TOX_PROXY_TYPE_HTTP, TOX_PROXY_TYPE_SOCKS5:
+		if len(options.ProxyHost) == 0 {
+			return &OptionsError{"ProxyHost", ErrOptionProxyHostMissing}
+		}
+		if options.ProxyPort == 0 {
+			return &OptionsError{"ProxyPort", ErrOptionProxyPortMissing}
+		}
+	default:
+		return &OptionsError{"ProxyType", ErrOptionInvalidValue}
+	}
+
+	// max ProxyHost length is 255
+	if len(options.ProxyHost) > 255 {
+		return &OptionsError{"ProxyHost", ErrOptionProxyHostTooLong}
+	}
+
+	switch options.SaveDataType {
+	case TOX_SAVEDATA_TYPE_NONE:
+		if len(options.SaveData) != 0 {
+			return &OptionsError{"SaveData", ErrOptionSaveDataUnused}
+		}
+	case TOX_SAVEDATA_TYPE_TOX_SAVE:
+		if len(options.SaveData) == 0 {
+			return &OptionsError{"SaveData", ErrOptionSaveDataMissing}
+		}
+	case TOX_SAVEDATA_TYPE_SECRET_KEY:
+		if len(options.SaveData) != TOX_SECRET_KEY_SIZE {
+			return &OptionsError{"SaveData", ErrOptionSecretKeySize}
+		}
+	default:
+		return &OptionsError{"SaveDataType", ErrOptionInvalidValue}
+	}
+
+	if options.Storage != nil && (options.SaveDataType != TOX_SAVEDATA_TYPE_NONE || len(options.SaveData) != 0) {
+		return &OptionsError{"Storage", ErrOptionStorageSaveData}
+	}
+
+	if options.LogLevel > TOX_LOG_LEVEL_ERROR {
+		return &OptionsError{"LogLevel", ErrOptionInvalidValue}
+	}
+
+	return nil
+}
+
+/* apply copies options to cOptions using the tox_options_set_* accessors. The
+ * returned function frees the C memory referenced by cOptions; call it once
+ * tox_new has returned. */
+func (options *Options) apply(cOptions *C.struct_Tox_Options) (func(), error) {
+	var cMemory []unsafe.Pointer
+	var cSaveData unsafe.Pointer
+	var cSaveDataSize C.size_t
+	free := func() {
+		// the savedata holds the secret key, don't leave it in freed memory
+		if cSaveData != nil {
+			C.memset(cSaveData, 0, cSaveDataSize)
+		}
+		for _, p := range cMemory {
+			C.free(p)
+		}
+	}
+
+	C.tox_options_set_ipv6_enabled(cOptions, C.bool(options.IPv6Enabled))
+	C.tox_options_set_udp_enabled(cOptions, C.bool(options.UDPEnabled))
+	C.tox_options_set_local_discovery_enabled(cOptions, C.bool(!options.LocalDiscoveryDisabled))
+	C.tox_options_set_hole_punching_enabled(cOptions, C.bool(!options.HolePunchingDisabled))
+
+	var cProxyType C.TOX_PROXY_TYPE = C.TOX_PROXY_TYPE_NONE
+	if options.ProxyType == TOX_PROXY_TYPE_HTTP {
+		cProxyType = C.TOX_PROXY_TYPE_HTTP
+	} else if options.ProxyType == TOX_PROXY_TYPE_SOCKS5 {
+		cProxyType = C.TOX_PROXY_TYPE_SOCKS5
+	}
+	C.tox_options_set_proxy_type(cOptions, cProxyType)
+
+	cProxyHost := C.CString(options.ProxyHost)
+	cMemory = append(cMemory, unsafe.Pointer(cProxyHost))
+	C.tox_options_set_proxy_host(cOptions, cProxyHost)
+
+	C.tox_options_set_proxy_port(cOptions, C.uint16_t(options.ProxyPort))
+	C.tox_options_set_start_port(cOptions, C.uint16_t(options.StartPort))
+	C.tox_options_set_end_port(cOptions, C.uint16_t(options.EndPort))
+	C.tox_options_set_tcp_port(cOptions, C.uint16_t(options.TcpPort))
+
+	var cSaveDataType C.TOX_SAVEDATA_TYPE = C.TOX_SAVEDATA_TYPE_NONE
+	if options.SaveDataType == TOX_SAVEDATA_TYPE_TOX_SAVE {
+		cSaveDataType = C.TOX_SAVEDATA_TYPE_TOX_SAVE
+	} else if options.SaveDataType == TOX_SAVEDATA_TYPE_SECRET_KEY {
+		cSaveDataType = C.TOX_SAVEDATA_TYPE_SECRET_KEY
+	}
+	C.tox_options_set_savedata_type(cOptions, cSaveDataType)
+
+	// the savedata is copied to C memory, C must not keep pointers to Go memory
+	if len(options.SaveData) > 0 {
+		cSaveData = C.CBytes(options.SaveData)
+		cSaveDataSize = C.size_t(len(options.SaveData))
+		cMemory = append(cMemory, cSaveData)
+		C.tox_options_set_savedata_data(cOptions, (*C.uint8_t)(cSaveData), cSaveDataSize)
+	} else {
+		C.tox_options_set_savedata_data(cOptions, nil, 0)
+	}
+
+	// Options added in later toxcore versions only fail if they are set to a
+	// value other than the toxcore default.
+	if !bool(C.options_set_experimental_thread_safety(cOptions, C.bool(options.ExperimentalThreadSafety))) && options.ExperimentalThreadSafety {
+		return free, &OptionsError{"ExperimentalThreadSafety", ErrOptionUnsupported}
+	}
+	if !bool(C.options_set_dht_announcements_enabled(cOptions, C.bool(!options.DHTAnnouncementsDisabled))) && options.DHTAnnouncementsDisabled {
+		return free, &OptionsError{"DHTAnnouncementsDisabled", ErrOptionUnsupported}
+	}
+	if !bool(C.options_set_experimental_groups_persistence(cOptions, C.bool(options.ExperimentalGroupsPersistence))) && options.ExperimentalGroupsPersistence {
+		return free, &OptionsError{"ExperimentalGroupsPersistence", ErrOptionUnsupported}
+	}
+	if !bool(C.options_set_experimental_disable_dns(cOptions, C.bool(options.ExperimentalDisableDNS))) && options.ExperimentalDisableDNS {
+		return free, &OptionsError{"ExperimentalDisableDNS", ErrOptionUnsupported}
+	}
+
+	return free, nil
+}
@@ -0,0 +1,305 @@
+package gotox
+
+import "sort"
+import "sync"
+import "time"
+
+/* OutgoingRequest is a friend request we sent that has not been accepted
+ * yet. */
+type OutgoingRequest struct {
+	// The friend number may change when the profile is reloaded, it is
+	// updated by NewRequestTracker.
+	FriendNumber uint32
+	PublicKey    PublicKey
+	Message      string
+	Sent         time.Time
+}
+
+/* RequestTrackerOptions configures a RequestTracker. */
+type RequestTrackerOptions struct {
+	/* The pending requests are loaded from and saved to this file as JSON,
+	 * e.g. next to the savedata. If empty, they are only kept in memory. */
+	StateFile string
+
+	/* Delete friends that haven't accepted our request after this long. If 0,
+	 * requests never expire. */
+	Expiry time.Duration
+}
+
+/* This event is triggered when a RequestTracker deleted a friend whose request
+ * expired. */
+type OnRequestExpired func(tox *Tox, request OutgoingRequest)
+
+/* RequestTracker tracks outgoing friend requests. A request is pending until
+ * the friend connects for the first time, which toxcore reports with the
+ * friend connection status callback.
+ *
+ * Send friend requests with the FriendAdd method of the tracker, or pass the
+ * friend number of requests sent otherwise to Track. */
+type RequestTracker struct {
+	tox     *Tox
+	options RequestTrackerOptions
+
+	mtx     sync.Mutex
+	pending map[PublicKey]OutgoingRequest
+	timer   *time.Timer
+	closed  bool
+
+	unsubscribe func()
+
+	handlers      handlerList
+	errorHandlers handlerList
+}
+
+/* NewRequestTracker creates a RequestTracker for t and loads the pending
+ * requests. Requests for friends that no longer exist are dropped. */
+func NewRequestTracker(t *Tox, options *RequestTrackerOptions) (*RequestTracker, error) {
+	r := &RequestTracker{tox: t, pending: make(map[PublicKey]OutgoingRequest)}
+	if options != nil {
+		r.options = *options
+	}
+
+	var requests []OutgoingRequest
+	if r.options.StateFile != "" {
+		if err := readJSONFile(r.options.StateFile, &requests); err != nil {
+			return nil, err
+		}
+	}
+
+	err := r.tox.withLock(func(t *Tox) error {
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+
+		for _, request := range requests {
+			n, err := t.FriendByPublicKey(request.PublicKey)
+			if err != nil {
+				continue
+			}
+			// the friend has been online, so the request was accepted
+			if last, err := t.FriendGetLastOnline(n); err == nil && !last.IsZero() {
+				continue
+			}
+			request.FriendNumber = n
+			r.pending[request.PublicKey] = request
+		}
+		return r.persist()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.unsubscribe, err = t.Subscribe(OnFriendConnectionStatusChanges(func(t *Tox, friendnumber uint32, connectionstatus ToxConnection) {
+		r.connected(t, friendnumber)
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	r.mtx.Lock()
+	r.schedule()
+	r.mtx.Unlock()
+
+	return r, nil
+}
+
+/* Close stops tracking and expiring requests. */
+func (r *RequestTracker) Close() {
+	r.mtx.Lock()
+	r.closed = true
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	r.mtx.Unlock()
+
+	r.unsubscribe()
+}
+
+/* FriendAdd sends a friend request like Tox.FriendAdd and tracks it. Once the
+ * request has been sent, the friend number is returned even if the state file
+ * can't be written; that error goes to the OnSaveError handlers. It must not
+ * be called from a callback; use Do instead. */
+func (r *RequestTracker) FriendAdd(address ToxID, message string) (uint32, error) {
+	var friendNumber uint32
+	var saveErr error
+
+	err := r.tox.withLock(func(t *Tox) error {
+		var err error
+		if friendNumber, err = t.FriendAdd(address, message); err != nil {
+			return err
+		}
+
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+
+		saveErr = r.add(OutgoingRequest{friendNumber, address.PublicKey(), message, time.Now()})
+		return nil
+	})
+
+	if saveErr != nil {
+		r.saveFailed(saveErr)
+	}
+	return friendNumber, err
+}
+
+/* Track starts tracking a friend request sent with Tox.FriendAdd. It must not
+ * be called from a callback; use Do instead. */
+func (r *RequestTracker) Track(friendNumber uint32, message string) error {
+	return r.tox.withLock(func(t *Tox) error {
+		publicKey, err := t.FriendGetPublickey(friendNumber)
+		if err != nil {
+			return err
+		}
+
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+
+		return r.add(OutgoingRequest{friendNumber, publicKey, message, time.Now()})
+	})
+}
+
+/* PendingRequests returns the requests that have not been accepted yet, oldest
+ * first. */
+func (r *RequestTracker) PendingRequests() []OutgoingRequest {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.sorted()
+}
+
+/* sorted returns the pending requests, oldest first. r.mtx must be held. */
+func (r *RequestTracker) sorted() []OutgoingRequest {
+	requests := make([]OutgoingRequest, 0, len(r.pending))
+	for _, request := range r.pending {
+		requests = append(requests, request)
+	}
+
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Sent.Before(requests[j].Sent) })
+	return requests
+}
+
+/* OnExpired subscribes f to expired requests. Subscribing nil has no effect. */
+func (r *RequestTracker) OnExpired(f OnRequestExpired) (unsubscribe func()) {
+	if f == nil {
+		return func() {}
+	}
+	return r.handlers.add(f)
+}
+
+/* OnSaveError subscribes f to errors writing the state file when a request
+ * was sent with FriendAdd, accepted or expired. Subscribing nil has no
+ * effect. */
+func (r *RequestTracker) OnSaveError(f OnSaveError) (unsubscribe func()) {
+	if f == nil {
+		return func() {}
+	}
+	return r.errorHandlers.add(f)
+}
+
+/* add records a request. r.mtx must be held. */
+func (r *RequestTracker) add(request OutgoingRequest) error {
+	r.pending[request.PublicKey] = request
+	r.schedule()
+	return r.persist()
+}
+
+/* connected marks the request of a friend as accepted. t is the *Tox passed
+ * to the callback. */
+func (r *RequestTracker) connected(t *Tox, friendNumber uint32) {
+	publicKey, err := t.FriendGetPublickey(friendNumber)
+	if err != nil {
+		return
+	}
+
+	r.mtx.Lock()
+	if _, ok := r.pending[publicKey]; ok {
+		delete(r.pending, publicKey)
+		err = r.persist()
+	}
+	r.mtx.Unlock()
+
+	if err != nil {
+		r.saveFailed(err)
+	}
+}
+
+/* expire deletes the friends whose request has expired. */
+func (r *RequestTracker) expire() {
+	var expired []OutgoingRequest
+	var saveErr error
+
+	r.tox.withLock(func(t *Tox) error {
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+
+		if r.closed {
+			return nil
+		}
+
+		now := time.Now()
+		for publicKey, request := range r.pending {
+			if now.Sub(request.Sent) < r.options.Expiry {
+				continue
+			}
+
+			delete(r.pending, publicKey)
+			n, err := t.FriendByPublicKey(publicKey)
+			if err != nil {
+				// already deleted
+				continue
+			}
+			if t.FriendDelete(n) == nil {
+				request.FriendNumber = n
+				expired = append(expired, request)
+			}
+		}
+
+		r.schedule()
+		saveErr = r.persist()
+		return nil
+	})
+
+	if saveErr != nil {
+		r.saveFailed(saveErr)
+	}
+
+	for _, request := range expired {
+		for _, h := range r.handlers.get() {
+			h.f.(OnRequestExpired)(r.tox, request)
+		}
+	}
+}
+
+func (r *RequestTracker) saveFailed(err error) {
+	for _, h := range r.errorHandlers.get() {
+		h.f.(OnSaveError)(err)
+	}
+}
+
+/* schedule arms the timer for the next expiry. r.mtx must be held. */
+func (r *RequestTracker) schedule() {
+	if r.options.Expiry <= 0 || r.closed || len(r.pending) == 0 {
+		return
+	}
+
+	var next time.Time
+	for _, request := range r.pending {
+		if at := request.Sent.Add(r.options.Expiry); next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+
+	if r.timer == nil {
+		r.timer = time.AfterFunc(time.Until(next), r.expire)
+	} else {
+		r.timer.Reset(time.Until(next))
+	}
+}
+
+/* persist writes the state file. r.mtx must be held. */
+func (r *RequestTracker) persist() error {
+	if r.options.StateFile == "" {
+		return nil
+	}
+
+	return writeJSONFile(r.options.StateFile, r.sorted())
+}
@@ -0,0 +1,67 @@
+package gotox
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+/* PanicError describes a panic recovered from an event handler. */
+type PanicError struct {
+	/* The event that was being dispatched. */
+	Event Event
+
+	/* The value passed to panic. */
+	Value interface{}
+
+	/* The stack trace of the panicking goroutine. */
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("Panic in %T handler: %v", e.Event, e.Value)
+}
+
+/* PanicHandler is called with every panic recovered from an event handler. */
+type PanicHandler func(err *PanicError)
+
+/* SetPanicHandler sets the function that is called when an event handler
+ * panics. The panic is recovered before it can unwind through toxcore, the
+ * remaining handlers still receive the event and Iterate returns the first
+ * *PanicError of the iteration. If a PanicHandler is set, Run reports panics
+ * only to the handler and keeps running. */
+func (t *Tox) SetPanicHandler(handler PanicHandler) {
+	if t.lock() != nil {
+		return
+	}
+	defer t.unlock()
+
+	t.panicHandler = handler
+}
+
+/* guard calls f and recovers a panic raised by it. The Tox lock must be held. */
+func (t *Tox) guard(ev Event, f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := &PanicError{Event: ev, Value: r, Stack: debug.Stack()}
+			if t.panicErr == nil {
+				t.panicErr = err
+			}
+			t.reportPanic(err)
+		}
+	}()
+
+	f()
+}
+
+/* reportPanic passes err to the PanicHandler, ignoring a panic in the handler
+ * itself. */
+func (t *Tox) reportPanic(err *PanicError) {
+	if t.panicHandler == nil {
+		return
+	}
+
+	defer func() {
+		recover()
+	}()
+	t.panicHandler(err)
+}
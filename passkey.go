@@ -0,0 +1,127 @@
+package gotox
+
+//#include <tox/toxencryptsave.h>
+import "C"
+import "runtime"
+import "sync"
+
+/* PassKey is a key derived from a passphrase, with the salt it was derived
+ * with. Deriving a key is slow on purpose, a PassKey makes repeated
+ * encryption cheap without keeping the passphrase around.
+ *
+ * The key lives in C memory, call Destroy to zero and free it. */
+type PassKey struct {
+	mtx sync.Mutex
+	key *C.Tox_Pass_Key
+}
+
+/* DerivePassKey derives a key from passphrase with a random salt. */
+func DerivePassKey(passphrase []byte) (*PassKey, error) {
+	var toxErrKeyDerivation C.TOX_ERR_KEY_DERIVATION
+	key := C.tox_pass_key_derive(passBytes(passphrase), C.size_t(len(passphrase)), &toxErrKeyDerivation)
+
+	if key == nil || ToxErrKeyDerivation(toxErrKeyDerivation) != TOX_ERR_KEY_DERIVATION_OK {
+		return nil, newToxError("DerivePassKey", ToxErrKeyDerivation(toxErrKeyDerivation))
+	}
+
+	return newPassKey(key), nil
+}
+
+/* DerivePassKeyWithSalt derives a key from passphrase and salt. Use the salt
+ * of encrypted data (see GetSalt) to get the key that decrypts it. */
+func DerivePassKeyWithSalt(passphrase []byte, salt []byte) (*PassKey, error) {
+	if len(salt) != TOX_PASS_SALT_LENGTH {
+		return nil, ErrArgs
+	}
+
+	var toxErrKeyDerivation C.TOX_ERR_KEY_DERIVATION
+	key := C.tox_pass_key_derive_with_salt(passBytes(passphrase), C.size_t(len(passphrase)), (*C.uint8_t)(&salt[0]), &toxErrKeyDerivation)
+
+	if key == nil || ToxErrKeyDerivation(toxErrKeyDerivation) != TOX_ERR_KEY_DERIVATION_OK {
+		return nil, newToxError("DerivePassKeyWithSalt", ToxErrKeyDerivation(toxErrKeyDerivation))
+	}
+
+	return newPassKey(key), nil
+}
+
+func newPassKey(key *C.Tox_Pass_Key) *PassKey {
+	k := &PassKey{key: key}
+	runtime.SetFinalizer(k, (*PassKey).Destroy)
+	return k
+}
+
+/* Encrypt encrypts plaintext like PassEncrypt, with the salt of the key. */
+func (k *PassKey) Encrypt(plaintext []byte) ([]byte, error) {
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+
+	if k.key == nil {
+		return nil, ErrPassKeyDestroyed
+	}
+
+	ciphertext := make([]byte, len(plaintext)+TOX_PASS_ENCRYPTION_EXTRA_LENGTH)
+
+	var toxErrEncryption C.TOX_ERR_ENCRYPTION
+	C.tox_pass_key_encrypt(k.key, passBytes(plaintext), C.size_t(len(plaintext)), (*C.uint8_t)(&ciphertext[0]), &toxErrEncryption)
+
+	if ToxErrEncryption(toxErrEncryption) != TOX_ERR_ENCRYPTION_OK {
+		return nil, newToxError("PassKey.Encrypt", ToxErrEncryption(toxErrEncryption))
+	}
+
+	return ciphertext, nil
+}
+
+/* Decrypt decrypts data encrypted with this key, i.e. with the same
+ * passphrase and salt. */
+func (k *PassKey) Decrypt(ciphertext []byte) ([]byte, error) {
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+
+	if k.key == nil {
+		return nil, ErrPassKeyDestroyed
+	}
+	if len(ciphertext) < TOX_PASS_ENCRYPTION_EXTRA_LENGTH {
+		return nil, newToxError("PassKey.Decrypt", TOX_ERR_DECRYPTION_INVALID_LENGTH)
+	}
+
+	plaintext := make([]byte, len(ciphertext)-TOX_PASS_ENCRYPTION_EXTRA_LENGTH)
+
+	var toxErrDecryption C.TOX_ERR_DECRYPTION
+	C.tox_pass_key_decrypt(k.key, (*C.uint8_t)(&ciphertext[0]), C.size_t(len(ciphertext)), passBytes(plaintext), &toxErrDecryption)
+
+	if ToxErrDecryption(toxErrDecryption) != TOX_ERR_DECRYPTION_OK {
+		return nil, newToxError("PassKey.Decrypt", ToxErrDecryption(toxErrDecryption))
+	}
+
+	return plaintext, nil
+}
+
+/* Destroy zeroes and frees the key. Later calls to Encrypt and Decrypt return
+ * ErrPassKeyDestroyed. Destroy may be called more than once. */
+func (k *PassKey) Destroy() {
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+
+	if k.key == nil {
+		return
+	}
+
+	// tox_pass_key_free zeroes the key before freeing it
+	C.tox_pass_key_free(k.key)
+	k.key = nil
+	runtime.SetFinalizer(k, nil)
+}
+
+/* GetEncryptedSavedataWithKey returns the savedata encrypted with key. */
+func (t *Tox) GetEncryptedSavedataWithKey(key *PassKey) ([]byte, error) {
+	plaintext, err := t.GetSavedata()
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := key.Encrypt(plaintext)
+	for i := range plaintext {
+		plaintext[i] = 0
+	}
+	return ciphertext, err
+}
@@ -0,0 +1,279 @@
+package gotox
+
+import "sync"
+import "sync/atomic"
+import "time"
+
+/* DefaultSaveDelay is used by a ProfileStore if ProfileStoreOptions.Delay is
+ * 0. */
+const DefaultSaveDelay = 2 * time.Second
+
+/* ProfileStoreOptions configures a ProfileStore. */
+type ProfileStoreOptions struct {
+	/* The file the savedata is written to. Ignored if Storage is set. */
+	Path string
+
+	/* Keep this many older versions as Path.1 (the newest) to Path.N. Ignored
+	 * if Storage is set. */
+	Backups int
+
+	/* Save the savedata to this Storage instead of the file at Path. */
+	Storage Storage
+
+	/* Save this long after the last change, so a burst of changes is saved
+	 * once. */
+	Delay time.Duration
+
+	/* If set, the savedata is encrypted with this key. To load an existing
+	 * encrypted profile, derive the key with the salt of that profile, see
+	 * ProfileStore.Salt. */
+	PassKey *PassKey
+}
+
+/* OnSaveError is called when state saved in the background, like the profile
+ * of a ProfileStore, could not be written. The save is retried with the next
+ * change. */
+type OnSaveError func(err error)
+
+/* ProfileStore writes the savedata of a Tox instance to a Storage whenever its
+ * state changes. By default, this is a FileStorage for the file at
+ * ProfileStoreOptions.Path.
+ *
+ * Saves are triggered by the bindings that change the savedata (e.g.
+ * SelfSetName, FriendAdd, SelfSetNospam) and by changes of friends reported
+ * by the callbacks (name, status message, status, connection). Call Flush
+ * before Kill to write pending changes. */
+type ProfileStore struct {
+	options ProfileStoreOptions
+	storage Storage
+
+	mtx         sync.Mutex
+	tox         *Tox
+	timer       *time.Timer
+	unsubscribe []func()
+
+	// Counts the changes, and the changes included in the stored savedata.
+	// There are pending changes while they differ.
+	changes uint64
+	saved   uint64
+
+	errorHandlers handlerList
+
+	// Counts the savedata taken from the instance, see write
+	generation atomic.Uint64
+
+	// Serializes the saves and protects written, the generation of the
+	// stored savedata
+	saveMtx sync.Mutex
+	written uint64
+}
+
+/* NewProfileStore creates a ProfileStore. Load the profile with Load, pass it
+ * to New and call Attach with the new instance. */
+func NewProfileStore(options *ProfileStoreOptions) (*ProfileStore, error) {
+	if options == nil || (options.Path == "" && options.Storage == nil) {
+		return nil, ErrArgs
+	}
+
+	s := &ProfileStore{options: *options, storage: options.Storage}
+	if s.storage == nil {
+		s.storage = NewFileStorage(options.Path, options.Backups)
+	}
+	if s.options.Delay <= 0 {
+		s.options.Delay = DefaultSaveDelay
+	}
+	return s, nil
+}
+
+/* Load reads the profile, decrypting it with the PassKey if it is encrypted.
+ * It returns nil and no error if there is no profile yet. */
+func (s *ProfileStore) Load() ([]byte, error) {
+	data, err := s.storage.Load()
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	if s.options.PassKey != nil && IsDataEncrypted(data) {
+		return s.options.PassKey.Decrypt(data)
+	}
+	return data, nil
+}
+
+/* Salt returns the salt of the encrypted profile, to derive the PassKey with
+ * DerivePassKeyWithSalt. */
+func (s *ProfileStore) Salt() ([]byte, error) {
+	data, err := s.storage.Load()
+	if err != nil {
+		return nil, err
+	}
+	return GetSalt(data)
+}
+
+/* OnSaveError registers f to be called whenever a save fails, including the
+ * saves running in the background. */
+func (s *ProfileStore) OnSaveError(f OnSaveError) (unsubscribe func()) {
+	return s.errorHandlers.add(f)
+}
+
+/* Attach starts saving the profile of t on changes. It returns an error if
+ * t has been killed. */
+func (s *ProfileStore) Attach(t *Tox) error {
+	// Subscribe before taking s.mtx: the Tox lock is always taken first, as
+	// changed is called with it held.
+	var unsubscribe []func()
+	handlers := []interface{}{
+		OnFriendNameChanges(func(*Tox, uint32, string) { s.changed() }),
+		OnFriendStatusMessageChanges(func(*Tox, uint32, string) { s.changed() }),
+		OnFriendStatusChanges(func(*Tox, uint32, ToxUserStatus) { s.changed() }),
+		OnFriendConnectionStatusChanges(func(*Tox, uint32, ToxConnection) { s.changed() }),
+	}
+	for _, handler := range handlers {
+		f, err := t.Subscribe(handler)
+		if err != nil {
+			for _, f := range unsubscribe {
+				f()
+			}
+			return err
+		}
+		unsubscribe = append(unsubscribe, f)
+	}
+	unsubscribe = append(unsubscribe, t.stateHandlers.add(s.changed))
+
+	s.mtx.Lock()
+	previous := s.unsubscribe
+	s.tox = t.ownerTox()
+	s.unsubscribe = unsubscribe
+	s.mtx.Unlock()
+
+	for _, f := range previous {
+		f()
+	}
+	return nil
+}
+
+/* Detach stops saving on changes. Pending changes are not saved, call Flush
+ * first. */
+func (s *ProfileStore) Detach() {
+	s.mtx.Lock()
+	unsubscribe := s.unsubscribe
+	s.unsubscribe = nil
+	s.tox = nil
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.mtx.Unlock()
+
+	for _, f := range unsubscribe {
+		f()
+	}
+}
+
+/* Flush saves pending changes right away. It must not be called from a
+ * callback; use Do instead. */
+func (s *ProfileStore) Flush() error {
+	s.mtx.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	pending := s.changes != s.saved
+	s.mtx.Unlock()
+
+	// A save started by the timer doesn't count until it has written the
+	// changes, so they are saved again rather than lost if Kill follows.
+	if !pending {
+		return nil
+	}
+	return s.save()
+}
+
+/* Save saves the profile of the attached instance, whether it changed or
+ * not. It must not be called from a callback; use Do instead. */
+func (s *ProfileStore) Save() error {
+	return s.save()
+}
+
+/* stateChanged notifies the ProfileStore, if any, that the savedata has
+ * changed. The bindings call it with the lock held. */
+func (t *toxInstance) stateChanged() {
+	for _, h := range t.stateHandlers.get() {
+		h.f.(func())()
+	}
+}
+
+/* changed schedules a save. It is called with the Tox lock held, so it must
+ * not call into the Tox instance. */
+func (s *ProfileStore) changed() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.tox == nil {
+		return
+	}
+
+	s.changes++
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.options.Delay, func() { s.save() })
+	} else {
+		s.timer.Reset(s.options.Delay)
+	}
+}
+
+func (s *ProfileStore) save() error {
+	// On errors the changes stay pending, so they are saved again with the
+	// next change or Flush.
+	err := s.write()
+	if err != nil {
+		for _, h := range s.errorHandlers.get() {
+			h.f.(OnSaveError)(err)
+		}
+	}
+	return err
+}
+
+/* write gets the savedata and writes it to the storage, unless a save that
+ * started later has already written newer savedata. */
+func (s *ProfileStore) write() error {
+	s.mtx.Lock()
+	t := s.tox
+	changes := s.changes
+	s.mtx.Unlock()
+
+	if t == nil {
+		return ErrToxInit
+	}
+
+	// The savedata is taken without holding saveMtx, as saveMtx must not be
+	// taken before the Tox lock. The generation orders the saves instead.
+	if err := t.lock(); err != nil {
+		return err
+	}
+	generation := s.generation.Add(1)
+	var data []byte
+	var err error
+	if s.options.PassKey != nil {
+		data, err = t.lockedTox.GetEncryptedSavedataWithKey(s.options.PassKey)
+	} else {
+		data, err = t.lockedTox.GetSavedata()
+	}
+	t.unlock()
+	if err != nil {
+		return err
+	}
+
+	s.saveMtx.Lock()
+	defer s.saveMtx.Unlock()
+
+	if generation > s.written {
+		if err := s.storage.Save(data); err != nil {
+			return err
+		}
+		s.written = generation
+	}
+
+	s.mtx.Lock()
+	if changes > s.saved {
+		s.saved = changes
+	}
+	s.mtx.Unlock()
+	return nil
+}
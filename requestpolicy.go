@@ -0,0 +1,348 @@
+package gotox
+
+import "crypto/subtle"
+import "errors"
+import "regexp"
+import "strings"
+import "sync"
+import "time"
+
+/* FriendRequestDecision is the outcome of a FriendRequestRule. */
+type FriendRequestDecision int
+
+const (
+	FRIEND_REQUEST_NO_DECISION FriendRequestDecision = iota
+	FRIEND_REQUEST_ACCEPT
+	FRIEND_REQUEST_REJECT
+	FRIEND_REQUEST_QUEUE
+)
+
+/* FriendRequest is a received friend request. */
+type FriendRequest struct {
+	PublicKey PublicKey
+	Message   string
+	Received  time.Time
+}
+
+/* FriendRequestRule decides about a friend request. It returns
+ * FRIEND_REQUEST_NO_DECISION to leave the decision to the next rule, and a
+ * reason which is passed on to the audit event. */
+type FriendRequestRule func(request FriendRequest) (FriendRequestDecision, string)
+
+/* FriendRequestAudit records a decision of a FriendRequestPolicy. Manual
+ * decisions have the reason "approved" or "rejected". Err is set if the
+ * decision could not be carried out, e.g. if FriendAddNorequest failed. */
+type FriendRequestAudit struct {
+	Request  FriendRequest
+	Decision FriendRequestDecision
+	Reason   string
+	Time     time.Time
+	Err      error
+}
+
+/* This event is triggered for every decision of a FriendRequestPolicy. */
+type OnFriendRequestAudit func(tox *Tox, audit FriendRequestAudit)
+
+/* FriendRequestPolicyOptions configures a FriendRequestPolicy. */
+type FriendRequestPolicyOptions struct {
+	/* The rules are asked in order, the first decision counts. */
+	Rules []FriendRequestRule
+
+	/* Used if no rule decides. FRIEND_REQUEST_NO_DECISION rejects. */
+	Default FriendRequestDecision
+
+	/* At most this many requests are accepted automatically per hour, further
+	 * requests that would be accepted are queued. If 0, there is no limit. */
+	MaxAcceptPerHour int
+
+	/* The queue of pending requests is loaded from and saved to this file as
+	 * JSON. If empty, the queue is only kept in memory. */
+	PendingFile string
+}
+
+/* AllowKeys accepts requests from the given public keys. */
+func AllowKeys(keys ...PublicKey) FriendRequestRule {
+	set := make(map[PublicKey]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+
+	return func(request FriendRequest) (FriendRequestDecision, string) {
+		if set[request.PublicKey] {
+			return FRIEND_REQUEST_ACCEPT, "allowlist"
+		}
+		return FRIEND_REQUEST_NO_DECISION, ""
+	}
+}
+
+/* DenyKeys rejects requests from the given public keys. */
+func DenyKeys(keys ...PublicKey) FriendRequestRule {
+	set := make(map[PublicKey]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+
+	return func(request FriendRequest) (FriendRequestDecision, string) {
+		if set[request.PublicKey] {
+			return FRIEND_REQUEST_REJECT, "denylist"
+		}
+		return FRIEND_REQUEST_NO_DECISION, ""
+	}
+}
+
+/* MessageMatches decides requests whose message matches re. */
+func MessageMatches(re *regexp.Regexp, decision FriendRequestDecision) FriendRequestRule {
+	reason := "message matches " + re.String()
+
+	return func(request FriendRequest) (FriendRequestDecision, string) {
+		if re.MatchString(request.Message) {
+			return decision, reason
+		}
+		return FRIEND_REQUEST_NO_DECISION, ""
+	}
+}
+
+/* SharedSecret accepts requests whose message is the given secret, ignoring
+ * surrounding whitespace. */
+func SharedSecret(secret string) FriendRequestRule {
+	return func(request FriendRequest) (FriendRequestDecision, string) {
+		message := strings.TrimSpace(request.Message)
+		if subtle.ConstantTimeCompare([]byte(message), []byte(secret)) == 1 {
+			return FRIEND_REQUEST_ACCEPT, "shared secret"
+		}
+		return FRIEND_REQUEST_NO_DECISION, ""
+	}
+}
+
+/* FriendRequestPolicy handles incoming friend requests according to a list of
+ * rules. Accepted requests are added with FriendAddNorequest, queued requests
+ * wait for Approve or Reject. */
+type FriendRequestPolicy struct {
+	tox     *Tox
+	options FriendRequestPolicyOptions
+
+	mtx      sync.Mutex
+	pending  []FriendRequest
+	accepted []time.Time
+
+	unsubscribe func()
+
+	handlers handlerList
+}
+
+/* NewFriendRequestPolicy starts handling the friend requests of t. Call Close
+ * to stop. */
+func NewFriendRequestPolicy(t *Tox, options *FriendRequestPolicyOptions) (*FriendRequestPolicy, error) {
+	p := &FriendRequestPolicy{tox: t}
+	if options != nil {
+		p.options = *options
+	}
+
+	pending, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	p.pending = pending
+
+	p.unsubscribe, err = t.Subscribe(OnFriendRequest(func(t *Tox, publickey PublicKey, message string) {
+		p.handle(t, FriendRequest{publickey, message, time.Now()})
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+/* Close stops handling friend requests. Pending requests can still be
+ * approved or rejected. */
+func (p *FriendRequestPolicy) Close() {
+	p.unsubscribe()
+}
+
+/* Pending returns the queued requests, oldest first. */
+func (p *FriendRequestPolicy) Pending() []FriendRequest {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	return append([]FriendRequest(nil), p.pending...)
+}
+
+/* Approve adds the friend of a queued request. If that fails, the request
+ * stays queued, so the approval can be retried. It must not be called from a
+ * callback; use Do instead. */
+func (p *FriendRequestPolicy) Approve(publicKey PublicKey) error {
+	request, err := p.take(publicKey)
+	if err == ErrFriendRequestNotPending {
+		return err
+	}
+
+	if err == nil {
+		if _, err = p.tox.FriendAddNorequest(publicKey); err != nil {
+			err = errors.Join(err, p.restore(request))
+		}
+	}
+
+	p.emit(p.tox, FriendRequestAudit{request, FRIEND_REQUEST_ACCEPT, "approved", time.Now(), err})
+	return err
+}
+
+/* Reject removes a queued request. */
+func (p *FriendRequestPolicy) Reject(publicKey PublicKey) error {
+	request, err := p.take(publicKey)
+	if err == ErrFriendRequestNotPending {
+		return err
+	}
+
+	p.emit(p.tox, FriendRequestAudit{request, FRIEND_REQUEST_REJECT, "rejected", time.Now(), err})
+	return err
+}
+
+/* OnAudit subscribes f to the decisions of the policy. Subscribing nil has no
+ * effect. */
+func (p *FriendRequestPolicy) OnAudit(f OnFriendRequestAudit) (unsubscribe func()) {
+	if f == nil {
+		return func() {}
+	}
+	return p.handlers.add(f)
+}
+
+/* handle decides about a request. t is the *Tox passed to the callback. */
+func (p *FriendRequestPolicy) handle(t *Tox, request FriendRequest) {
+	decision, reason := p.decide(request)
+	audit := FriendRequestAudit{request, decision, reason, time.Now(), nil}
+
+	p.mtx.Lock()
+	if decision == FRIEND_REQUEST_ACCEPT && !p.allowAccept(audit.Time) {
+		decision = FRIEND_REQUEST_QUEUE
+		audit.Decision = decision
+		audit.Reason = reason + ", hourly limit reached"
+	}
+	if decision == FRIEND_REQUEST_QUEUE {
+		p.remove(request.PublicKey)
+		p.pending = append(p.pending, request)
+		audit.Err = p.persist()
+	}
+	p.mtx.Unlock()
+
+	if decision == FRIEND_REQUEST_ACCEPT {
+		if _, audit.Err = t.FriendAddNorequest(request.PublicKey); audit.Err != nil {
+			// the request was not accepted, so it doesn't count
+			p.mtx.Lock()
+			p.releaseAccept(audit.Time)
+			p.mtx.Unlock()
+		}
+	}
+
+	p.emit(t, audit)
+}
+
+func (p *FriendRequestPolicy) decide(request FriendRequest) (FriendRequestDecision, string) {
+	for _, rule := range p.options.Rules {
+		if decision, reason := rule(request); decision != FRIEND_REQUEST_NO_DECISION {
+			return decision, reason
+		}
+	}
+
+	if p.options.Default == FRIEND_REQUEST_NO_DECISION {
+		return FRIEND_REQUEST_REJECT, "default"
+	}
+	return p.options.Default, "default"
+}
+
+/* allowAccept records an accepted request unless the hourly limit has been
+ * reached. p.mtx must be held. */
+func (p *FriendRequestPolicy) allowAccept(now time.Time) bool {
+	if p.options.MaxAcceptPerHour <= 0 {
+		return true
+	}
+
+	accepted := p.accepted[:0]
+	for _, at := range p.accepted {
+		if now.Sub(at) < time.Hour {
+			accepted = append(accepted, at)
+		}
+	}
+	p.accepted = accepted
+
+	if len(p.accepted) >= p.options.MaxAcceptPerHour {
+		return false
+	}
+	p.accepted = append(p.accepted, now)
+	return true
+}
+
+/* releaseAccept forgets the accepted request recorded at the given time by
+ * allowAccept. p.mtx must be held. */
+func (p *FriendRequestPolicy) releaseAccept(at time.Time) {
+	for i := len(p.accepted) - 1; i >= 0; i-- {
+		if p.accepted[i].Equal(at) {
+			p.accepted = append(p.accepted[:i], p.accepted[i+1:]...)
+			return
+		}
+	}
+}
+
+/* take removes the queued request of publicKey. If the pending file can't be
+ * written, the request stays queued. */
+func (p *FriendRequestPolicy) take(publicKey PublicKey) (FriendRequest, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	// remove copies the slice, so pending is left intact
+	pending := p.pending
+	request, ok := p.remove(publicKey)
+	if !ok {
+		return request, ErrFriendRequestNotPending
+	}
+	if err := p.persist(); err != nil {
+		p.pending = pending
+		return request, err
+	}
+	return request, nil
+}
+
+/* restore queues a request removed by take again, as the oldest one. */
+func (p *FriendRequestPolicy) restore(request FriendRequest) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.pending = append([]FriendRequest{request}, p.pending...)
+	return p.persist()
+}
+
+/* remove removes the queued request of publicKey. p.mtx must be held. */
+func (p *FriendRequestPolicy) remove(publicKey PublicKey) (FriendRequest, bool) {
+	for i, request := range p.pending {
+		if request.PublicKey == publicKey {
+			p.pending = append(p.pending[:i:i], p.pending[i+1:]...)
+			return request, true
+		}
+	}
+	return FriendRequest{}, false
+}
+
+func (p *FriendRequestPolicy) load() ([]FriendRequest, error) {
+	var pending []FriendRequest
+	if p.options.PendingFile == "" {
+		return pending, nil
+	}
+
+	err := readJSONFile(p.options.PendingFile, &pending)
+	return pending, err
+}
+
+/* persist writes the pending file. p.mtx must be held. */
+func (p *FriendRequestPolicy) persist() error {
+	if p.options.PendingFile == "" {
+		return nil
+	}
+
+	return writeJSONFile(p.options.PendingFile, p.pending)
+}
+
+func (p *FriendRequestPolicy) emit(t *Tox, audit FriendRequestAudit) {
+	for _, h := range p.handlers.get() {
+		h.f.(OnFriendRequestAudit)(t, audit)
+	}
+}
@@ -0,0 +1,281 @@
+package gotox
+
+import "sort"
+import "sync"
+import "time"
+
+/* RosterChangeKind describes what changed about a friend in a Roster. */
+type RosterChangeKind int
+
+const (
+	ROSTER_FRIEND_ADDED RosterChangeKind = iota
+	ROSTER_FRIEND_REMOVED
+	ROSTER_FRIEND_ONLINE
+	ROSTER_FRIEND_OFFLINE
+	ROSTER_CONNECTION_CHANGED
+	ROSTER_NAME_CHANGED
+	ROSTER_STATUS_MESSAGE_CHANGED
+	ROSTER_STATUS_CHANGED
+	ROSTER_TYPING_CHANGED
+)
+
+/* RosterChange is passed to the OnRosterChange handlers. Friend is the state
+ * after the change and Previous the state before. For ROSTER_FRIEND_ADDED
+ * Previous is empty, for ROSTER_FRIEND_REMOVED Friend is the last known state. */
+type RosterChange struct {
+	Kind     RosterChangeKind
+	Friend   FriendSnapshot
+	Previous FriendSnapshot
+}
+
+/* OnRosterChange is called for every change a Roster detects. */
+type OnRosterChange func(tox *Tox, change RosterChange)
+
+/* Roster is an in-memory model of the friend list. It is loaded once from
+ * toxcore and then kept up to date by the name, status message, status,
+ * connection status and typing callbacks, so reading it doesn't call into
+ * toxcore. All methods are safe for concurrent use.
+ *
+ * toxcore has no callbacks for friends being added or deleted. A friend the
+ * Roster doesn't know yet is added once any of its callbacks fire; call
+ * Refresh after FriendAdd or FriendDelete to update the Roster right away. */
+type Roster struct {
+	tox *Tox
+
+	mtx     sync.RWMutex
+	friends map[uint32]FriendSnapshot
+
+	unsubscribe []func()
+
+	handlers handlerList
+}
+
+/* NewRoster creates a Roster for t and loads the friend list. Call Close to
+ * stop updating the Roster. */
+func NewRoster(t *Tox) (*Roster, error) {
+	r := &Roster{tox: t, friends: make(map[uint32]FriendSnapshot)}
+
+	handlers := []interface{}{
+		OnFriendNameChanges(func(t *Tox, friendnumber uint32, name string) {
+			r.update(t, friendnumber, func(s *FriendSnapshot) { s.Name = name })
+		}),
+		OnFriendStatusMessageChanges(func(t *Tox, friendnumber uint32, message string) {
+			r.update(t, friendnumber, func(s *FriendSnapshot) { s.StatusMessage = message })
+		}),
+		OnFriendStatusChanges(func(t *Tox, friendnumber uint32, userstatus ToxUserStatus) {
+			r.update(t, friendnumber, func(s *FriendSnapshot) { s.Status = userstatus })
+		}),
+		OnFriendConnectionStatusChanges(func(t *Tox, friendnumber uint32, connectionstatus ToxConnection) {
+			r.update(t, friendnumber, func(s *FriendSnapshot) {
+				if s.Connection != TOX_CONNECTION_NONE && connectionstatus == TOX_CONNECTION_NONE {
+					s.LastOnline = time.Now()
+				}
+				s.Connection = connectionstatus
+			})
+		}),
+		OnFriendTypingChanges(func(t *Tox, friendnumber uint32, istyping bool) {
+			r.update(t, friendnumber, func(s *FriendSnapshot) { s.Typing = istyping })
+		}),
+	}
+	for _, handler := range handlers {
+		unsubscribe, err := t.Subscribe(handler)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.unsubscribe = append(r.unsubscribe, unsubscribe)
+	}
+
+	if err := r.Refresh(); err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+/* Close stops updating the Roster. The last known state can still be read. */
+func (r *Roster) Close() {
+	for _, unsubscribe := range r.unsubscribe {
+		unsubscribe()
+	}
+}
+
+/* Get returns the last known state of the friend with the given number. */
+func (r *Roster) Get(friendNumber uint32) (FriendSnapshot, bool) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	s, ok := r.friends[friendNumber]
+	return s, ok
+}
+
+/* Friends returns the last known state of all friends, ordered by friend
+ * number. */
+func (r *Roster) Friends() []FriendSnapshot {
+	r.mtx.RLock()
+	friends := make([]FriendSnapshot, 0, len(r.friends))
+	for _, s := range r.friends {
+		friends = append(friends, s)
+	}
+	r.mtx.RUnlock()
+
+	sort.Slice(friends, func(i, j int) bool { return friends[i].Number < friends[j].Number })
+	return friends
+}
+
+/* Len returns the number of friends. */
+func (r *Roster) Len() int {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return len(r.friends)
+}
+
+/* OnChange subscribes f to the changes of the Roster. The handlers are called
+ * from Iterate, like other callbacks, or from Refresh. Subscribing nil has no
+ * effect. */
+func (r *Roster) OnChange(f OnRosterChange) (unsubscribe func()) {
+	if f == nil {
+		return func() {}
+	}
+	return r.handlers.add(f)
+}
+
+/* Refresh reloads the whole friend list from toxcore and reports the
+ * differences to the last known state. It must not be called from a callback;
+ * use Do instead. */
+func (r *Roster) Refresh() error {
+	changes, err := r.reload()
+	if err != nil {
+		return err
+	}
+
+	r.emit(r.tox, changes)
+	return nil
+}
+
+/* reload reads all friends while holding the Tox lock, so no callback can
+ * update the Roster in between, and replaces the known state. */
+func (r *Roster) reload() ([]RosterChange, error) {
+	if err := r.tox.lock(); err != nil {
+		return nil, err
+	}
+	defer r.tox.unlock()
+
+	t := r.tox.lockedTox
+	numbers, err := t.SelfGetFriendlist()
+	if err != nil {
+		return nil, err
+	}
+
+	friends := make(map[uint32]FriendSnapshot, len(numbers))
+	for _, n := range numbers {
+		f, err := t.Friend(n)
+		if err != nil {
+			return nil, err
+		}
+		if friends[n], err = f.Snapshot(); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var changes []RosterChange
+	for n, prev := range r.friends {
+		if cur, ok := friends[n]; !ok || cur.PublicKey != prev.PublicKey {
+			changes = append(changes, RosterChange{Kind: ROSTER_FRIEND_REMOVED, Friend: prev, Previous: prev})
+		}
+	}
+	for n, cur := range friends {
+		if prev, ok := r.friends[n]; ok && cur.PublicKey == prev.PublicKey {
+			changes = append(changes, rosterDiff(prev, cur)...)
+		} else {
+			changes = append(changes, RosterChange{Kind: ROSTER_FRIEND_ADDED, Friend: cur})
+		}
+	}
+	r.friends = friends
+
+	return changes, nil
+}
+
+/* update applies a change reported by a callback. t is the *Tox passed to the
+ * callback. */
+func (r *Roster) update(t *Tox, friendNumber uint32, apply func(s *FriendSnapshot)) {
+	r.mtx.Lock()
+	prev, ok := r.friends[friendNumber]
+	if ok {
+		cur := prev
+		apply(&cur)
+		r.friends[friendNumber] = cur
+		r.mtx.Unlock()
+
+		r.emit(t, rosterDiff(prev, cur))
+		return
+	}
+	r.mtx.Unlock()
+
+	// A friend added since the last Refresh, the snapshot already includes
+	// the change.
+	f, err := t.Friend(friendNumber)
+	if err != nil {
+		return
+	}
+	cur, err := f.Snapshot()
+	if err != nil {
+		return
+	}
+
+	r.mtx.Lock()
+	r.friends[friendNumber] = cur
+	r.mtx.Unlock()
+
+	r.emit(t, []RosterChange{{Kind: ROSTER_FRIEND_ADDED, Friend: cur}})
+}
+
+func (r *Roster) emit(t *Tox, changes []RosterChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	handlers := r.handlers.get()
+	for _, change := range changes {
+		for _, h := range handlers {
+			h.f.(OnRosterChange)(t, change)
+		}
+	}
+}
+
+/* rosterDiff returns the changes between two states of the same friend. */
+func rosterDiff(prev, cur FriendSnapshot) []RosterChange {
+	var changes []RosterChange
+	add := func(kind RosterChangeKind) {
+		changes = append(changes, RosterChange{Kind: kind, Friend: cur, Previous: prev})
+	}
+
+	if prev.Connection != cur.Connection {
+		if prev.Connection == TOX_CONNECTION_NONE {
+			add(ROSTER_FRIEND_ONLINE)
+		} else if cur.Connection == TOX_CONNECTION_NONE {
+			add(ROSTER_FRIEND_OFFLINE)
+		} else {
+			add(ROSTER_CONNECTION_CHANGED)
+		}
+	}
+	if prev.Name != cur.Name {
+		add(ROSTER_NAME_CHANGED)
+	}
+	if prev.StatusMessage != cur.StatusMessage {
+		add(ROSTER_STATUS_MESSAGE_CHANGED)
+	}
+	if prev.Status != cur.Status {
+		add(ROSTER_STATUS_CHANGED)
+	}
+	if prev.Typing != cur.Typing {
+		add(ROSTER_TYPING_CHANGED)
+	}
+
+	return changes
+}
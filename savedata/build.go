@@ -0,0 +1,177 @@
+package savedata
+
+import "crypto/ecdh"
+import "crypto/rand"
+import "encoding/binary"
+import "errors"
+import "fmt"
+
+var (
+	ErrNoKeys       = errors.New("NospamKeys missing")
+	ErrFieldTooLong = errors.New("Field too long")
+	ErrNoRequest    = errors.New("Request message missing")
+)
+
+/* New returns a Savedata for a profile with the given secret key and nospam.
+ * The public key is derived from the secret key. Set the other fields before
+ * calling Marshal. */
+func New(secretKey SecretKey, nospam uint32) (*Savedata, error) {
+	privateKey, err := ecdh.X25519().NewPrivateKey(secretKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	keys := &NospamKeys{Nospam: nospam, SecretKey: secretKey}
+	copy(keys.PublicKey[:], privateKey.PublicKey().Bytes())
+	return &Savedata{NospamKeys: keys}, nil
+}
+
+/* Generate returns a Savedata for a new profile with a random secret key and
+ * nospam. */
+func Generate() (*Savedata, error) {
+	var secretKey SecretKey
+	var nospam [4]byte
+	if _, err := rand.Read(secretKey[:]); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nospam[:]); err != nil {
+		return nil, err
+	}
+
+	return New(secretKey, binary.BigEndian.Uint32(nospam[:]))
+}
+
+/* Marshal encodes the savedata in the format toxcore loads with
+ * TOX_SAVEDATA_TYPE_TOX_SAVE. The sections are written in the order toxcore
+ * uses, the Other sections last. */
+func (s *Savedata) Marshal() ([]byte, error) {
+	if s.NospamKeys == nil {
+		return nil, ErrNoKeys
+	}
+	if len(s.Name) > MAX_NAME_LENGTH {
+		return nil, fmt.Errorf("Name: %w", ErrFieldTooLong)
+	}
+	if len(s.StatusMessage) > MAX_STATUS_MESSAGE_LENGTH {
+		return nil, fmt.Errorf("StatusMessage: %w", ErrFieldTooLong)
+	}
+
+	data := make([]byte, headerSize)
+	binary.LittleEndian.PutUint32(data[4:], stateCookieGlobal)
+
+	keys := make([]byte, nospamKeysSize)
+	binary.BigEndian.PutUint32(keys, s.NospamKeys.Nospam)
+	copy(keys[4:], s.NospamKeys.PublicKey[:])
+	copy(keys[4+PUBLIC_KEY_SIZE:], s.NospamKeys.SecretKey[:])
+	data = appendSection(data, stateCookieType, uint32(SECTION_NOSPAMKEYS), keys)
+
+	if len(s.DHTNodes) > 0 {
+		nodes, err := encodeNodes(s.DHTNodes)
+		if err != nil {
+			return nil, fmt.Errorf("DHTNodes: %w", err)
+		}
+		dht := binary.LittleEndian.AppendUint32(nil, dhtStateCookieGlobal)
+		dht = appendSection(dht, dhtStateCookieType, dhtStateTypeNodes, nodes)
+		data = appendSection(data, stateCookieType, uint32(SECTION_DHT), dht)
+	}
+
+	friends := make([]byte, 0, len(s.Friends)*friendSize)
+	for i, friend := range s.Friends {
+		var err error
+		if friends, err = appendFriend(friends, friend); err != nil {
+			return nil, fmt.Errorf("Friends[%d]: %w", i, err)
+		}
+	}
+	data = appendSection(data, stateCookieType, uint32(SECTION_FRIENDS), friends)
+
+	data = appendSection(data, stateCookieType, uint32(SECTION_NAME), s.Name)
+	data = appendSection(data, stateCookieType, uint32(SECTION_STATUSMESSAGE), s.StatusMessage)
+	data = appendSection(data, stateCookieType, uint32(SECTION_STATUS), []byte{byte(s.Status)})
+
+	for _, section := range []struct {
+		name  string
+		typ   SectionType
+		nodes []Node
+	}{
+		{"TCPRelays", SECTION_TCP_RELAY, s.TCPRelays},
+		{"PathNodes", SECTION_PATH_NODE, s.PathNodes},
+	} {
+		if len(section.nodes) == 0 {
+			continue
+		}
+		nodes, err := encodeNodes(section.nodes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", section.name, err)
+		}
+		data = appendSection(data, stateCookieType, uint32(section.typ), nodes)
+	}
+
+	for _, section := range s.Other {
+		data = appendSection(data, stateCookieType, uint32(section.Type), section.Data)
+	}
+
+	return appendSection(data, stateCookieType, uint32(SECTION_END), nil), nil
+}
+
+func appendSection(data []byte, cookie uint32, sectionType uint32, section []byte) []byte {
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(section)))
+	data = binary.LittleEndian.AppendUint32(data, cookie<<16|sectionType)
+	return append(data, section...)
+}
+
+func encodeNodes(nodes []Node) ([]byte, error) {
+	var data []byte
+	for _, node := range nodes {
+		ip := node.IP.To4()
+		family := byte(familyIPv4)
+		if ip == nil {
+			ip = node.IP.To16()
+			family = familyIPv6
+		}
+		if ip == nil {
+			return nil, ErrBadNode
+		}
+		if node.TCP {
+			family += familyTCPIPv4 - familyIPv4
+		}
+
+		data = append(data, family)
+		data = append(data, ip...)
+		data = binary.BigEndian.AppendUint16(data, node.Port)
+		data = append(data, node.PublicKey[:]...)
+	}
+
+	return data, nil
+}
+
+/* appendFriend encodes a friend entry. toxcore drops friends in the states
+ * ADDED or REQUESTED without a request message, so these are rejected. */
+func appendFriend(data []byte, friend Friend) ([]byte, error) {
+	if len(friend.RequestMessage) > MAX_FRIEND_REQUEST_LENGTH || len(friend.Name) > MAX_NAME_LENGTH ||
+		len(friend.StatusMessage) > MAX_STATUS_MESSAGE_LENGTH {
+		return nil, ErrFieldTooLong
+	}
+	if (friend.Status == FRIEND_STATUS_ADDED || friend.Status == FRIEND_STATUS_REQUESTED) && len(friend.RequestMessage) == 0 {
+		return nil, ErrNoRequest
+	}
+
+	data = append(data, byte(friend.Status))
+	data = append(data, friend.PublicKey[:]...)
+	data = appendPadded(data, friend.RequestMessage, MAX_FRIEND_REQUEST_LENGTH)
+	data = appendPadded(data, friend.Name, MAX_NAME_LENGTH)
+	data = appendPadded(data, friend.StatusMessage, MAX_STATUS_MESSAGE_LENGTH)
+	data = append(data, byte(friend.UserStatus))
+	data = binary.BigEndian.AppendUint32(data, friend.RequestNospam)
+
+	var lastSeen uint64
+	if !friend.LastSeen.IsZero() {
+		lastSeen = uint64(friend.LastSeen.Unix())
+	}
+	return binary.BigEndian.AppendUint64(data, lastSeen), nil
+}
+
+/* appendPadded appends b padded with zeros to size, followed by its length. */
+func appendPadded(data []byte, b []byte, size int) []byte {
+	data = append(data, b...)
+	data = append(data, make([]byte, size-len(b))...)
+	return binary.BigEndian.AppendUint16(data, uint16(len(b)))
+}
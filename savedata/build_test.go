@@ -0,0 +1,83 @@
+package savedata
+
+import "net"
+import "reflect"
+import "testing"
+import "time"
+
+func TestMarshalParse(t *testing.T) {
+	s, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Name = []byte("Alice")
+	s.StatusMessage = []byte("Testing gotox")
+	s.Status = USERSTATUS_AWAY
+	s.Friends = []Friend{
+		{
+			Status:        FRIEND_STATUS_CONFIRMED,
+			PublicKey:     PublicKey{1, 2, 3},
+			Name:          []byte("Bob"),
+			StatusMessage: []byte("Out for lunch"),
+			UserStatus:    USERSTATUS_BUSY,
+			LastSeen:      time.Unix(1700000000, 0),
+		},
+		{
+			Status:         FRIEND_STATUS_ADDED,
+			PublicKey:      PublicKey{4, 5, 6},
+			RequestMessage: []byte("Hi, this is Alice"),
+			RequestNospam:  0x12345678,
+		},
+	}
+	s.TCPRelays = []Node{
+		{TCP: true, IP: net.IPv4(127, 0, 0, 1).To4(), Port: 443, PublicKey: PublicKey{7}},
+		{TCP: true, IP: net.ParseIP("2a01:4f8::42"), Port: 3389, PublicKey: PublicKey{8}},
+	}
+
+	data, err := s.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(parsed.NospamKeys, s.NospamKeys) {
+		t.Errorf("NospamKeys: got %+v, want %+v", parsed.NospamKeys, s.NospamKeys)
+	}
+	if string(parsed.Name) != string(s.Name) {
+		t.Errorf("Name: got %q, want %q", parsed.Name, s.Name)
+	}
+	if string(parsed.StatusMessage) != string(s.StatusMessage) {
+		t.Errorf("StatusMessage: got %q, want %q", parsed.StatusMessage, s.StatusMessage)
+	}
+	if parsed.Status != s.Status {
+		t.Errorf("Status: got %d, want %d", parsed.Status, s.Status)
+	}
+
+	if len(parsed.Friends) != len(s.Friends) {
+		t.Fatalf("got %d friends, want %d", len(parsed.Friends), len(s.Friends))
+	}
+	for i, want := range s.Friends {
+		got := parsed.Friends[i]
+		if got.Status != want.Status || got.PublicKey != want.PublicKey ||
+			string(got.RequestMessage) != string(want.RequestMessage) ||
+			string(got.Name) != string(want.Name) ||
+			string(got.StatusMessage) != string(want.StatusMessage) ||
+			got.UserStatus != want.UserStatus || got.RequestNospam != want.RequestNospam ||
+			!got.LastSeen.Equal(want.LastSeen) {
+			t.Errorf("Friends[%d]: got %+v, want %+v", i, got, want)
+		}
+	}
+
+	if len(parsed.TCPRelays) != len(s.TCPRelays) {
+		t.Fatalf("got %d TCP relays, want %d", len(parsed.TCPRelays), len(s.TCPRelays))
+	}
+	for i, want := range s.TCPRelays {
+		got := parsed.TCPRelays[i]
+		if got.TCP != want.TCP || !got.IP.Equal(want.IP) || got.Port != want.Port || got.PublicKey != want.PublicKey {
+			t.Errorf("TCPRelays[%d]: got %+v, want %+v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package savedata
+
+import "encoding/binary"
+import "errors"
+import "fmt"
+import "net"
+import "time"
+
+const (
+	stateCookieGlobal    = 0x15ed1b1f
+	stateCookieType      = 0x01ce
+	dhtStateCookieGlobal = 0x0159000d
+	dhtStateCookieType   = 0x11ce
+	dhtStateTypeNodes    = 4
+
+	headerSize        = 8
+	sectionHeaderSize = 8
+	nospamKeysSize    = 4 + PUBLIC_KEY_SIZE + SECRET_KEY_SIZE
+
+	// size of a friend entry: status, public key, request message, its length,
+	// name, its length, status message, its length, user status, request
+	// nospam, last seen
+	friendSize = 1 + PUBLIC_KEY_SIZE + MAX_FRIEND_REQUEST_LENGTH + 2 + MAX_NAME_LENGTH + 2 +
+		MAX_STATUS_MESSAGE_LENGTH + 2 + 1 + 4 + 8
+
+	// address families of packed nodes
+	familyIPv4    = 2
+	familyIPv6    = 10
+	familyTCPIPv4 = 130
+	familyTCPIPv6 = 138
+)
+
+var (
+	ErrBadHeader = errors.New("Not a toxcore savedata")
+	ErrTruncated = errors.New("Data truncated")
+	ErrBadCookie = errors.New("Bad section cookie")
+	ErrBadLength = errors.New("Bad section length")
+	ErrBadNode   = errors.New("Bad node")
+	ErrNoEnd     = errors.New("End marker missing")
+)
+
+/* SectionError reports a corrupt section. Offset is the position of the
+ * section header in the savedata. */
+type SectionError struct {
+	Type   SectionType
+	Offset int
+	Err    error
+}
+
+func (e *SectionError) Error() string {
+	return fmt.Sprintf("Section %v at offset %d: %v", e.Type, e.Offset, e.Err)
+}
+
+func (e *SectionError) Unwrap() error {
+	return e.Err
+}
+
+/* Parse decodes savedata. Corrupt sections don't stop the parsing, instead a
+ * *SectionError is reported for each of them, joined with errors.Join, next
+ * to everything that could be decoded. Only ErrBadHeader is returned with a
+ * nil Savedata. */
+func Parse(data []byte) (*Savedata, error) {
+	if len(data) < headerSize || binary.LittleEndian.Uint32(data) != 0 ||
+		binary.LittleEndian.Uint32(data[4:]) != stateCookieGlobal {
+		return nil, ErrBadHeader
+	}
+
+	s := &Savedata{}
+	var errs []error
+
+	offset := headerSize
+	for {
+		if len(data)-offset < sectionHeaderSize {
+			if offset == len(data) {
+				errs = append(errs, &SectionError{SECTION_END, offset, ErrNoEnd})
+			} else {
+				errs = append(errs, &SectionError{SECTION_END, offset, ErrTruncated})
+			}
+			break
+		}
+
+		length := binary.LittleEndian.Uint32(data[offset:])
+		cookieType := binary.LittleEndian.Uint32(data[offset+4:])
+		sectionType := SectionType(cookieType & 0xffff)
+
+		if cookieType>>16 != stateCookieType {
+			errs = append(errs, &SectionError{sectionType, offset, ErrBadCookie})
+			break
+		}
+
+		start := offset + sectionHeaderSize
+		if uint64(length) > uint64(len(data)-start) {
+			errs = append(errs, &SectionError{sectionType, offset, ErrTruncated})
+			break
+		}
+
+		if sectionType == SECTION_END {
+			break
+		}
+
+		if err := s.decodeSection(sectionType, data[start:start+int(length)]); err != nil {
+			errs = append(errs, &SectionError{sectionType, offset, err})
+		}
+		offset = start + int(length)
+	}
+
+	return s, errors.Join(errs...)
+}
+
+func (s *Savedata) decodeSection(sectionType SectionType, data []byte) error {
+	var err error
+
+	switch sectionType {
+	case SECTION_NOSPAMKEYS:
+		if len(data) != nospamKeysSize {
+			return ErrBadLength
+		}
+		keys := &NospamKeys{Nospam: binary.BigEndian.Uint32(data)}
+		copy(keys.PublicKey[:], data[4:])
+		copy(keys.SecretKey[:], data[4+PUBLIC_KEY_SIZE:])
+		s.NospamKeys = keys
+
+	case SECTION_DHT:
+		s.DHTNodes, err = decodeDHT(data)
+
+	case SECTION_FRIENDS:
+		s.Friends, err = decodeFriends(data)
+
+	case SECTION_NAME:
+		if len(data) > MAX_NAME_LENGTH {
+			return ErrBadLength
+		}
+		s.Name = append([]byte(nil), data...)
+
+	case SECTION_STATUSMESSAGE:
+		if len(data) > MAX_STATUS_MESSAGE_LENGTH {
+			return ErrBadLength
+		}
+		s.StatusMessage = append([]byte(nil), data...)
+
+	case SECTION_STATUS:
+		if len(data) != 1 {
+			return ErrBadLength
+		}
+		s.Status = UserStatus(data[0])
+
+	case SECTION_TCP_RELAY:
+		s.TCPRelays, err = decodeNodes(data)
+
+	case SECTION_PATH_NODE:
+		s.PathNodes, err = decodeNodes(data)
+
+	default:
+		s.Other = append(s.Other, Section{sectionType, append([]byte(nil), data...)})
+	}
+
+	return err
+}
+
+/* decodeDHT decodes the DHT section, which is made of subsections like the
+ * savedata itself. Only the nodes subsection is decoded. */
+func decodeDHT(data []byte) ([]Node, error) {
+	if len(data) < 4 || binary.LittleEndian.Uint32(data) != dhtStateCookieGlobal {
+		return nil, ErrBadCookie
+	}
+
+	var nodes []Node
+	offset := 4
+	for offset < len(data) {
+		if len(data)-offset < sectionHeaderSize {
+			return nodes, ErrTruncated
+		}
+
+		length := binary.LittleEndian.Uint32(data[offset:])
+		cookieType := binary.LittleEndian.Uint32(data[offset+4:])
+		if cookieType>>16 != dhtStateCookieType {
+			return nodes, ErrBadCookie
+		}
+
+		start := offset + sectionHeaderSize
+		if uint64(length) > uint64(len(data)-start) {
+			return nodes, ErrTruncated
+		}
+
+		if cookieType&0xffff == dhtStateTypeNodes {
+			decoded, err := decodeNodes(data[start : start+int(length)])
+			nodes = append(nodes, decoded...)
+			if err != nil {
+				return nodes, err
+			}
+		}
+		offset = start + int(length)
+	}
+
+	return nodes, nil
+}
+
+/* decodeNodes decodes a list of packed nodes: the address family, the IPv4 or
+ * IPv6 address, the port in network byte order and the public key. */
+func decodeNodes(data []byte) ([]Node, error) {
+	var nodes []Node
+	for len(data) > 0 {
+		var node Node
+		var ipSize int
+
+		switch data[0] {
+		case familyIPv4:
+			ipSize = net.IPv4len
+		case familyIPv6:
+			ipSize = net.IPv6len
+		case familyTCPIPv4:
+			node.TCP = true
+			ipSize = net.IPv4len
+		case familyTCPIPv6:
+			node.TCP = true
+			ipSize = net.IPv6len
+		default:
+			return nodes, ErrBadNode
+		}
+
+		size := 1 + ipSize + 2 + PUBLIC_KEY_SIZE
+		if len(data) < size {
+			return nodes, ErrTruncated
+		}
+
+		node.IP = append(net.IP(nil), data[1:1+ipSize]...)
+		node.Port = binary.BigEndian.Uint16(data[1+ipSize:])
+		copy(node.PublicKey[:], data[1+ipSize+2:])
+
+		nodes = append(nodes, node)
+		data = data[size:]
+	}
+
+	return nodes, nil
+}
+
+func decodeFriends(data []byte) ([]Friend, error) {
+	if len(data)%friendSize != 0 {
+		return nil, ErrBadLength
+	}
+
+	friends := make([]Friend, 0, len(data)/friendSize)
+	for ; len(data) > 0; data = data[friendSize:] {
+		friend, err := decodeFriend(data[:friendSize])
+		if err != nil {
+			return friends, err
+		}
+		friends = append(friends, friend)
+	}
+
+	return friends, nil
+}
+
+func decodeFriend(data []byte) (Friend, error) {
+	var friend Friend
+	r := reader{data: data}
+
+	friend.Status = FriendStatus(r.byte())
+	copy(friend.PublicKey[:], r.bytes(PUBLIC_KEY_SIZE))
+
+	request := r.bytes(MAX_FRIEND_REQUEST_LENGTH)
+	requestLength := int(r.uint16())
+	name := r.bytes(MAX_NAME_LENGTH)
+	nameLength := int(r.uint16())
+	statusMessage := r.bytes(MAX_STATUS_MESSAGE_LENGTH)
+	statusMessageLength := int(r.uint16())
+
+	if requestLength > MAX_FRIEND_REQUEST_LENGTH || nameLength > MAX_NAME_LENGTH ||
+		statusMessageLength > MAX_STATUS_MESSAGE_LENGTH {
+		return friend, ErrBadLength
+	}
+
+	friend.RequestMessage = append([]byte(nil), request[:requestLength]...)
+	friend.Name = append([]byte(nil), name[:nameLength]...)
+	friend.StatusMessage = append([]byte(nil), statusMessage[:statusMessageLength]...)
+	friend.UserStatus = UserStatus(r.byte())
+	friend.RequestNospam = binary.BigEndian.Uint32(r.bytes(4))
+
+	if lastSeen := binary.BigEndian.Uint64(r.bytes(8)); lastSeen != 0 {
+		friend.LastSeen = time.Unix(int64(lastSeen), 0)
+	}
+
+	return friend, nil
+}
+
+/* reader reads fixed size fields. The caller checks the total size. */
+type reader struct {
+	data []byte
+}
+
+func (r *reader) bytes(n int) []byte {
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) byte() byte {
+	return r.bytes(1)[0]
+}
+
+func (r *reader) uint16() uint16 {
+	return binary.BigEndian.Uint16(r.bytes(2))
+}
@@ -0,0 +1,102 @@
+package savedata
+
+import "errors"
+import "os"
+import "testing"
+import "time"
+
+/* testdata/profile.tox has the section layout toxcore writes: two DHT nodes,
+ * a confirmed friend and a friend we sent a request to, a TCP relay, a path
+ * node and an empty conference section. */
+func readProfile(t testing.TB) []byte {
+	data, err := os.ReadFile("testdata/profile.tox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseProfile(t *testing.T) {
+	s, err := Parse(readProfile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.NospamKeys == nil || s.NospamKeys.Nospam != 0x0badf00d {
+		t.Errorf("NospamKeys: got %+v", s.NospamKeys)
+	}
+	if string(s.Name) != "Alice" || string(s.StatusMessage) != "Testing gotox" || s.Status != USERSTATUS_BUSY {
+		t.Errorf("got name %q, status message %q, status %d", s.Name, s.StatusMessage, s.Status)
+	}
+	if len(s.DHTNodes) != 2 || s.DHTNodes[0].IP.String() != "85.143.221.42" || s.DHTNodes[1].IP.String() != "2a01:4f8::42" {
+		t.Errorf("DHTNodes: got %+v", s.DHTNodes)
+	}
+	if len(s.TCPRelays) != 1 || !s.TCPRelays[0].TCP || s.TCPRelays[0].Port != 443 {
+		t.Errorf("TCPRelays: got %+v", s.TCPRelays)
+	}
+	if len(s.PathNodes) != 1 || s.PathNodes[0].TCP {
+		t.Errorf("PathNodes: got %+v", s.PathNodes)
+	}
+	if len(s.Other) != 1 || s.Other[0].Type != SECTION_CONFERENCES {
+		t.Errorf("Other: got %+v", s.Other)
+	}
+
+	if len(s.Friends) != 2 {
+		t.Fatalf("got %d friends, want 2", len(s.Friends))
+	}
+	bob := s.Friends[0]
+	if bob.Status != FRIEND_STATUS_CONFIRMED || string(bob.Name) != "Bob" || !bob.LastSeen.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Friends[0]: got %+v", bob)
+	}
+	added := s.Friends[1]
+	if added.Status != FRIEND_STATUS_ADDED || string(added.RequestMessage) != "Hi, this is Alice" ||
+		added.RequestNospam != 0x12345678 || !added.LastSeen.IsZero() {
+		t.Errorf("Friends[1]: got %+v", added)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	profile := readProfile(f)
+	f.Add(profile)
+	f.Add(profile[:len(profile)/2])
+
+	s, err := Generate()
+	if err != nil {
+		f.Fatal(err)
+	}
+	s.Name = []byte("Alice")
+	s.TCPRelays = []Node{{TCP: true, IP: []byte{127, 0, 0, 1}, Port: 443}}
+	data, err := s.Marshal()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s, err := Parse(data)
+		if errors.Is(err, ErrBadHeader) {
+			if s != nil {
+				t.Error("Savedata returned with ErrBadHeader")
+			}
+			return
+		}
+		if s == nil {
+			t.Fatalf("nil Savedata with %v", err)
+		}
+		if err == nil {
+			return
+		}
+
+		joined, ok := err.(interface{ Unwrap() []error })
+		if !ok {
+			t.Fatalf("got %T, want errors joined with errors.Join", err)
+		}
+		for _, err := range joined.Unwrap() {
+			var sectionErr *SectionError
+			if !errors.As(err, &sectionErr) {
+				t.Errorf("got %v, want a *SectionError", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,153 @@
+/* Package savedata decodes the toxcore savedata format, as returned by
+ * Tox.GetSavedata, without cgo or a running Tox instance.
+ *
+ * The savedata starts with an 8 byte header and continues with sections, each
+ * with a little-endian uint32 length and a uint32 type whose upper 16 bits
+ * are a cookie. The last section is an end marker. Sections this package
+ * doesn't understand, like conferences, are kept as raw Sections. */
+package savedata
+
+import "encoding/hex"
+import "net"
+import "strings"
+import "time"
+
+const (
+	PUBLIC_KEY_SIZE           = 32
+	SECRET_KEY_SIZE           = 32
+	MAX_NAME_LENGTH           = 128
+	MAX_STATUS_MESSAGE_LENGTH = 1007
+	MAX_FRIEND_REQUEST_LENGTH = 1024
+)
+
+/* SectionType identifies a section of the savedata. */
+type SectionType uint16
+
+const (
+	SECTION_NOSPAMKEYS    SectionType = 1
+	SECTION_DHT           SectionType = 2
+	SECTION_FRIENDS       SectionType = 3
+	SECTION_NAME          SectionType = 4
+	SECTION_STATUSMESSAGE SectionType = 5
+	SECTION_STATUS        SectionType = 6
+	SECTION_GROUPS        SectionType = 7
+	SECTION_TCP_RELAY     SectionType = 10
+	SECTION_PATH_NODE     SectionType = 11
+	SECTION_CONFERENCES   SectionType = 20
+	SECTION_END           SectionType = 255
+)
+
+func (t SectionType) String() string {
+	switch t {
+	case SECTION_NOSPAMKEYS:
+		return "NospamKeys"
+	case SECTION_DHT:
+		return "DHT"
+	case SECTION_FRIENDS:
+		return "Friends"
+	case SECTION_NAME:
+		return "Name"
+	case SECTION_STATUSMESSAGE:
+		return "StatusMessage"
+	case SECTION_STATUS:
+		return "Status"
+	case SECTION_GROUPS:
+		return "Groups"
+	case SECTION_TCP_RELAY:
+		return "TCPRelay"
+	case SECTION_PATH_NODE:
+		return "PathNode"
+	case SECTION_CONFERENCES:
+		return "Conferences"
+	case SECTION_END:
+		return "End"
+	}
+	return "Unknown"
+}
+
+/* FriendStatus is the state of a friend entry. */
+type FriendStatus uint8
+
+const (
+	FRIEND_STATUS_NONE      FriendStatus = 0
+	FRIEND_STATUS_ADDED     FriendStatus = 1
+	FRIEND_STATUS_REQUESTED FriendStatus = 2
+	FRIEND_STATUS_CONFIRMED FriendStatus = 3
+	FRIEND_STATUS_ONLINE    FriendStatus = 4
+)
+
+/* UserStatus is the user status, with the values of gotox.ToxUserStatus. */
+type UserStatus uint8
+
+const (
+	USERSTATUS_NONE UserStatus = 0
+	USERSTATUS_AWAY UserStatus = 1
+	USERSTATUS_BUSY UserStatus = 2
+)
+
+/* PublicKey is a long term or DHT public key. */
+type PublicKey [PUBLIC_KEY_SIZE]byte
+
+func (key PublicKey) String() string {
+	return strings.ToUpper(hex.EncodeToString(key[:]))
+}
+
+/* SecretKey is the long term secret key. String redacts it. */
+type SecretKey [SECRET_KEY_SIZE]byte
+
+func (key SecretKey) String() string {
+	return "SecretKey(REDACTED)"
+}
+
+/* NospamKeys holds the nospam and the long term key pair. Nospam has the
+ * value returned by Tox.SelfGetNospam. */
+type NospamKeys struct {
+	Nospam    uint32
+	PublicKey PublicKey
+	SecretKey SecretKey
+}
+
+/* Node is a DHT node, TCP relay or onion path node. */
+type Node struct {
+	TCP       bool
+	IP        net.IP
+	Port      uint16
+	PublicKey PublicKey
+}
+
+/* Friend is an entry of the friend list. Friends we sent a request to have
+ * the status FRIEND_STATUS_ADDED, RequestMessage and RequestNospam are those
+ * of our request. */
+type Friend struct {
+	Status         FriendStatus
+	PublicKey      PublicKey
+	RequestMessage []byte
+	Name           []byte
+	StatusMessage  []byte
+	UserStatus     UserStatus
+	RequestNospam  uint32
+	// Zero if the friend was never seen online
+	LastSeen time.Time
+}
+
+/* Section is a raw section of the savedata. */
+type Section struct {
+	Type SectionType
+	Data []byte
+}
+
+/* Savedata is the decoded savedata. Name, StatusMessage and Friend names are
+ * kept as bytes, as toxcore doesn't enforce UTF-8. */
+type Savedata struct {
+	NospamKeys    *NospamKeys
+	DHTNodes      []Node
+	Friends       []Friend
+	Name          []byte
+	StatusMessage []byte
+	Status        UserStatus
+	TCPRelays     []Node
+	PathNodes     []Node
+
+	// Sections that are not decoded, in their original order
+	Other []Section
+}
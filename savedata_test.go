@@ -0,0 +1,73 @@
+package gotox
+
+import "testing"
+
+import "github.com/codedust/go-tox/savedata"
+
+/* toxcore loads a profile built with the savedata package. */
+func TestLoadSavedata(t *testing.T) {
+	s, err := savedata.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Name = []byte("Alice")
+	s.StatusMessage = []byte("Testing gotox")
+	s.Friends = []savedata.Friend{
+		{Status: savedata.FRIEND_STATUS_CONFIRMED, PublicKey: savedata.PublicKey{1, 2, 3}, Name: []byte("Bob")},
+		{Status: savedata.FRIEND_STATUS_CONFIRMED, PublicKey: savedata.PublicKey{4, 5, 6}, Name: []byte("Carol")},
+	}
+	data, err := s.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := testOptions()
+	options.SaveDataType = TOX_SAVEDATA_TYPE_TOX_SAVE
+	options.SaveData = data
+	tox, err := New(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tox.Kill()
+
+	name, err := tox.SelfGetName()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != string(s.Name) {
+		t.Errorf("SelfGetName: got %q, want %q", name, s.Name)
+	}
+
+	publicKey, err := tox.SelfGetPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if publicKey != PublicKey(s.NospamKeys.PublicKey) {
+		t.Errorf("SelfGetPublicKey: got %v, want %v", publicKey, s.NospamKeys.PublicKey)
+	}
+
+	nospam, err := tox.SelfGetNospam()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nospam != s.NospamKeys.Nospam {
+		t.Errorf("SelfGetNospam: got %08x, want %08x", nospam, s.NospamKeys.Nospam)
+	}
+
+	friends, err := tox.SelfGetFriendlist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(friends) != len(s.Friends) {
+		t.Fatalf("SelfGetFriendlist: got %d friends, want %d", len(friends), len(s.Friends))
+	}
+	for i, friendNumber := range friends {
+		publicKey, err := tox.FriendGetPublickey(friendNumber)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if publicKey != PublicKey(s.Friends[i].PublicKey) {
+			t.Errorf("friend %d: got %v, want %v", friendNumber, publicKey, s.Friends[i].PublicKey)
+		}
+	}
+}
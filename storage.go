@@ -0,0 +1,203 @@
+package gotox
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "sync"
+
+/* Storage stores a profile. Set Options.Storage to load the savedata from a
+ * Storage in New, and ProfileStoreOptions.Storage to save it there.
+ *
+ * FileStorage and MemoryStorage are provided, other implementations can keep
+ * profiles e.g. in a database. */
+type Storage interface {
+	/* Load returns the stored savedata, or nil and no error if nothing has
+	 * been stored yet. */
+	Load() ([]byte, error)
+
+	/* Save replaces the stored savedata. A failed Save must leave the previous
+	 * savedata intact. */
+	Save(data []byte) error
+
+	/* Lock reserves the profile for a single Tox instance. It fails with
+	 * ErrStorageLocked if the profile is already in use. */
+	Lock() (unlock func() error, err error)
+}
+
+/* newFromStorage loads the savedata from options.Storage, keeping it locked
+ * until the instance is killed, and creates the instance with create. */
+func newFromStorage(options *Options, create func(options *Options) (*Tox, error)) (*Tox, error) {
+	unlock, err := options.Storage.Lock()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := options.Storage.Load()
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+
+	loaded := *options
+	loaded.Storage = nil
+	if data != nil {
+		loaded.SaveDataType = TOX_SAVEDATA_TYPE_TOX_SAVE
+		loaded.SaveData = data
+	}
+
+	t, err := create(&loaded)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+
+	t.storage = options.Storage
+	t.resources.storageUnlock = unlock
+	return t, nil
+}
+
+/* Storage returns the Storage the instance was loaded from, or nil. */
+func (t *Tox) Storage() Storage {
+	if t.toxInstance == nil {
+		return nil
+	}
+	return t.storage
+}
+
+/* FileStorage stores the profile in a file. Save replaces the file
+ * atomically: the savedata is written to a temporary file, synced and
+ * renamed, so a crash leaves either the old or the new profile. */
+type FileStorage struct {
+	Path string
+
+	// Keep this many older versions as Path.1 (the newest) to Path.N.
+	Backups int
+}
+
+/* NewFileStorage returns a FileStorage for the file at path. */
+func NewFileStorage(path string, backups int) *FileStorage {
+	return &FileStorage{Path: path, Backups: backups}
+}
+
+func (s *FileStorage) Load() ([]byte, error) {
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (s *FileStorage) Save(data []byte) error {
+	path := s.Path
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if s.Backups > 0 {
+		for i := s.Backups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+		}
+		os.Remove(path + ".1")
+		if err := os.Link(path, path+".1"); err != nil && !os.IsNotExist(err) {
+			// the file system may not support hard links
+			if err := copyFile(path, path+".1"); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// sync the directory, so the rename survives a crash
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+/* Lock locks the file Path.lock. */
+func (s *FileStorage) Lock() (func() error, error) {
+	return lockFile(s.Path + ".lock")
+}
+
+func copyFile(src string, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0600)
+}
+
+/* MemoryStorage keeps the profile in memory, e.g. for tests or to hand the
+ * savedata to another storage layer. */
+type MemoryStorage struct {
+	mtx    sync.Mutex
+	data   []byte
+	locked bool
+}
+
+/* NewMemoryStorage returns a MemoryStorage holding a copy of data. */
+func NewMemoryStorage(data []byte) *MemoryStorage {
+	s := &MemoryStorage{}
+	if data != nil {
+		s.data = append([]byte(nil), data...)
+	}
+	return s
+}
+
+func (s *MemoryStorage) Load() ([]byte, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.data == nil {
+		return nil, nil
+	}
+	return append([]byte(nil), s.data...), nil
+}
+
+func (s *MemoryStorage) Save(data []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.data = append([]byte{}, data...)
+	return nil
+}
+
+func (s *MemoryStorage) Lock() (func() error, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.locked {
+		return nil, ErrStorageLocked
+	}
+	s.locked = true
+
+	var once sync.Once
+	return func() error {
+		once.Do(func() {
+			s.mtx.Lock()
+			s.locked = false
+			s.mtx.Unlock()
+		})
+		return nil
+	}, nil
+}
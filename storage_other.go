@@ -0,0 +1,19 @@
+//go:build !unix
+
+package gotox
+
+import "os"
+
+/* lockFile creates path exclusively and removes it on unlock. A lock left
+ * behind by a crash has to be removed by hand. */
+func lockFile(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0600)
+	if os.IsExist(err) {
+		return nil, ErrStorageLocked
+	} else if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	return func() error { return os.Remove(path) }, nil
+}
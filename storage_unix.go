@@ -0,0 +1,25 @@
+//go:build unix
+
+package gotox
+
+import "os"
+import "syscall"
+
+/* lockFile takes an exclusive flock on path. The lock is released by the
+ * kernel if the process dies, so a crash never leaves a stale lock. */
+func lockFile(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, ErrStorageLocked
+		}
+		return nil, err
+	}
+
+	return f.Close, nil
+}
@@ -0,0 +1,374 @@
+package gotox
+
+import "sync"
+
+/* hookKind identifies one of the toxcore callbacks. */
+type hookKind int
+
+const (
+	hookSelfConnectionStatus hookKind = iota
+	hookFriendName
+	hookFriendStatusMessage
+	hookFriendStatus
+	hookFriendConnectionStatus
+	hookFriendTyping
+	hookFriendReadReceipt
+	hookFriendRequest
+	hookFriendMessage
+	hookFileRecvControl
+	hookFileChunkRequest
+	hookFileRecv
+	hookFileRecvChunk
+	hookFriendLossyPacket
+	hookFriendLosslessPacket
+	hookCount
+)
+
+type subscriber struct {
+	id uint64
+	f  interface{}
+}
+
+/* subscribers holds the handlers registered with the On* functions. The slices
+ * are copied on write, so a dispatch in progress is not affected by handlers
+ * being added or removed. */
+type subscribers struct {
+	mtx    sync.Mutex
+	lists  [hookCount][]subscriber
+	nextID uint64
+}
+
+/* subscribe installs the C hook for kind and appends f to its handlers. */
+func (t *Tox) subscribe(kind hookKind, f interface{}) (func(), error) {
+	if err := t.lock(); err != nil {
+		return func() {}, err
+	}
+	defer t.unlock()
+
+	t.installHook(kind)
+
+	s := &t.subscribers
+	s.mtx.Lock()
+	s.nextID++
+	id := s.nextID
+	list := make([]subscriber, len(s.lists[kind]), len(s.lists[kind])+1)
+	copy(list, s.lists[kind])
+	s.lists[kind] = append(list, subscriber{id, f})
+	s.mtx.Unlock()
+
+	owner := t.ownerTox()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { owner.unsubscribe(kind, id) })
+	}, nil
+}
+
+/* unsubscribe removes a handler and unregisters the C hook for kind if
+ * nothing else is interested in it anymore. While the callbacks run, the lock
+ * is held by Iterate, possibly on the calling goroutine, so the hook is left
+ * to Iterate. */
+func (t *Tox) unsubscribe(kind hookKind, id uint64) {
+	if t.dispatching.Load() {
+		t.subscribers.remove(kind, id)
+		t.hooksStale.Store(true)
+		return
+	}
+
+	if err := t.lock(); err != nil {
+		// the hooks went away with the instance
+		t.subscribers.remove(kind, id)
+		return
+	}
+	defer t.unlock()
+
+	t.subscribers.remove(kind, id)
+	t.updateHook(kind, t.hasCallback(kind))
+}
+
+func (s *subscribers) remove(kind hookKind, id uint64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	list := make([]subscriber, 0, len(s.lists[kind]))
+	for _, sub := range s.lists[kind] {
+		if sub.id != id {
+			list = append(list, sub)
+		}
+	}
+	s.lists[kind] = list
+}
+
+func (s *subscribers) get(kind hookKind) []subscriber {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.lists[kind]
+}
+
+/*
+ * Functions to subscribe to events. Any number of handlers can be subscribed
+ * to the same event. They are called in the order they were subscribed, after
+ * the handler registered with the matching Callback* function. Each function
+ * returns a func that removes the handler again, and unregisters the C hook
+ * once nothing is interested in the event anymore; it may be called at any
+ * time, including from within a handler. Subscribing nil has no effect.
+ * Nothing is subscribed if the instance has been killed; use Subscribe to get
+ * the error.
+ */
+
+/* Subscribe subscribes handler, which must be a non-nil function of one of the
+ * On* callback types, like the matching On* function. It returns ErrArgs for
+ * other handlers, and ErrKilled or ErrToxInit if the instance can't be used.
+ * The returned func is never nil. */
+func (t *Tox) Subscribe(handler interface{}) (unsubscribe func(), err error) {
+	kind, ok := hookKindOf(handler)
+	if !ok {
+		return func() {}, ErrArgs
+	}
+	return t.subscribe(kind, handler)
+}
+
+/* hookKindOf returns the hook handler is subscribed to, and false if handler
+ * is nil or not a callback type. */
+func hookKindOf(handler interface{}) (hookKind, bool) {
+	switch f := handler.(type) {
+	case OnSelfConnectionStatusChanges:
+		return hookSelfConnectionStatus, f != nil
+	case OnFriendNameChanges:
+		return hookFriendName, f != nil
+	case OnFriendStatusMessageChanges:
+		return hookFriendStatusMessage, f != nil
+	case OnFriendStatusChanges:
+		return hookFriendStatus, f != nil
+	case OnFriendConnectionStatusChanges:
+		return hookFriendConnectionStatus, f != nil
+	case OnFriendTypingChanges:
+		return hookFriendTyping, f != nil
+	case OnFriendReadReceipt:
+		return hookFriendReadReceipt, f != nil
+	case OnFriendRequest:
+		return hookFriendRequest, f != nil
+	case OnFriendMessage:
+		return hookFriendMessage, f != nil
+	case OnFileRecvControl:
+		return hookFileRecvControl, f != nil
+	case OnFileChunkRequest:
+		return hookFileChunkRequest, f != nil
+	case OnFileRecv:
+		return hookFileRecv, f != nil
+	case OnFileRecvChunk:
+		return hookFileRecvChunk, f != nil
+	case OnFriendLossyPacket:
+		return hookFriendLossyPacket, f != nil
+	case OnFriendLosslessPacket:
+		return hookFriendLosslessPacket, f != nil
+	}
+	return 0, false
+}
+
+func (t *Tox) OnSelfConnectionStatusChanges(f OnSelfConnectionStatusChanges) (unsubscribe func()) {
+	unsubscribe, _ = t.Subscribe(f)
+	return unsubscribe
+}
+
+func (t *Tox) OnFriendNameChanges(f OnFriendNameChanges) (unsubscribe func()) {
+	unsubscribe, _ = t.Subscribe(f)
+	return unsubscribe
+}
+
+func (t *Tox) OnFriendStatusMessageChanges(f OnFriendStatusMessageChanges) (unsubscribe func()) {
+	unsubscribe, _ = t.Subscribe(f)
+	return unsubscribe
+}
+
+func (t *Tox) OnFriendStatusChanges(f OnFriendStatusChanges) (unsubscribe func()) {
+	unsubscribe, _ = t.Subscribe(f)
+	return unsubscribe
+}
+
+func (t *Tox) OnFriendConnectionStatusChanges(f OnFriendConnectionStatusChanges) (unsubscribe func()) {
+	unsubscribe, _ = t.Subscribe(f)
+	return unsubscribe
+}
+
+func (t *Tox) OnFriendTypingChanges(f OnFriendTypingChanges) (unsubscribe func()) {
+	unsubscribe, _ = t.Subscribe(f)
+	return unsubscribe
+}
+
+func (t *Tox) OnFriendReadReceipt(f OnFriendReadReceipt) (unsubscribe func()) {
+	unsubscribe, _ = t.Subscribe(f)
+	return unsubscribe
+}
+
+func (t *Tox) OnFriendRequest(f OnFriendRequest) (unsubscribe func()) {
+	unsubscribe, _ = t.Subscribe(f)
+	return unsubscribe
+}
+
+func (t *Tox) OnFriendMessage(f OnFriendMessage) (unsubscribe func()) {
+	unsubscribe, _ = t.Subscribe(f)
+	return unsubscribe
+}
+
+func (t *Tox) OnFileRecvControl(f OnFileRecvControl) (unsubscribe func()) {
+	unsubscribe, _ = t.Subscribe(f)
+	return unsubscribe
+}
+
+func (t *Tox) OnFileChunkRequest(f OnFileChunkRequest) (unsubscribe func()) {
+	unsubscribe, _ = t.Subscribe(f)
+	return unsubscribe
+}
+
+func (t *Tox) OnFileRecv(f OnFileRecv) (unsubscribe func()) {
+	unsubscribe, _ = t.Subscribe(f)
+	return unsubscribe
+}
+
+func (t *Tox) OnFileRecvChunk(f OnFileRecvChunk) (unsubscribe func()) {
+	unsubscribe, _ = t.Subscribe(f)
+	return unsubscribe
+}
+
+func (t *Tox) OnFriendLossyPacket(f OnFriendLossyPacket) (unsubscribe func()) {
+	unsubscribe, _ = t.Subscribe(f)
+	return unsubscribe
+}
+
+func (t *Tox) OnFriendLosslessPacket(f OnFriendLosslessPacket) (unsubscribe func()) {
+	unsubscribe, _ = t.Subscribe(f)
+	return unsubscribe
+}
+
+/* dispatch calls the Callback* handler and the subscribed handlers for ev and
+ * queues it for the event channel. It is called by the hooks during Iterate.
+ * Every handler runs through guard, so a panic never unwinds through the C
+ * frames of tox_iterate. */
+func (t *Tox) dispatch(ev Event) {
+	switch ev := ev.(type) {
+	case SelfConnectionStatusEvent:
+		if t.onSelfConnectionStatusChanges != nil {
+			t.guard(ev, func() { t.onSelfConnectionStatusChanges(t, ev.Status) })
+		}
+		for _, sub := range t.subscribers.get(hookSelfConnectionStatus) {
+			f := sub.f.(OnSelfConnectionStatusChanges)
+			t.guard(ev, func() { f(t, ev.Status) })
+		}
+	case FriendNameEvent:
+		if t.onFriendNameChanges != nil {
+			t.guard(ev, func() { t.onFriendNameChanges(t, ev.FriendNumber, ev.Name) })
+		}
+		for _, sub := range t.subscribers.get(hookFriendName) {
+			f := sub.f.(OnFriendNameChanges)
+			t.guard(ev, func() { f(t, ev.FriendNumber, ev.Name) })
+		}
+	case FriendStatusMessageEvent:
+		if t.onFriendStatusMessageChanges != nil {
+			t.guard(ev, func() { t.onFriendStatusMessageChanges(t, ev.FriendNumber, ev.Message) })
+		}
+		for _, sub := range t.subscribers.get(hookFriendStatusMessage) {
+			f := sub.f.(OnFriendStatusMessageChanges)
+			t.guard(ev, func() { f(t, ev.FriendNumber, ev.Message) })
+		}
+	case FriendStatusEvent:
+		if t.onFriendStatusChanges != nil {
+			t.guard(ev, func() { t.onFriendStatusChanges(t, ev.FriendNumber, ev.Status) })
+		}
+		for _, sub := range t.subscribers.get(hookFriendStatus) {
+			f := sub.f.(OnFriendStatusChanges)
+			t.guard(ev, func() { f(t, ev.FriendNumber, ev.Status) })
+		}
+	case FriendConnectionStatusEvent:
+		if t.onFriendConnectionStatusChanges != nil {
+			t.guard(ev, func() { t.onFriendConnectionStatusChanges(t, ev.FriendNumber, ev.Status) })
+		}
+		for _, sub := range t.subscribers.get(hookFriendConnectionStatus) {
+			f := sub.f.(OnFriendConnectionStatusChanges)
+			t.guard(ev, func() { f(t, ev.FriendNumber, ev.Status) })
+		}
+	case FriendTypingEvent:
+		if t.onFriendTypingChanges != nil {
+			t.guard(ev, func() { t.onFriendTypingChanges(t, ev.FriendNumber, ev.IsTyping) })
+		}
+		for _, sub := range t.subscribers.get(hookFriendTyping) {
+			f := sub.f.(OnFriendTypingChanges)
+			t.guard(ev, func() { f(t, ev.FriendNumber, ev.IsTyping) })
+		}
+	case FriendReadReceiptEvent:
+		if t.onFriendReadReceipt != nil {
+			t.guard(ev, func() { t.onFriendReadReceipt(t, ev.FriendNumber, ev.MessageID) })
+		}
+		for _, sub := range t.subscribers.get(hookFriendReadReceipt) {
+			f := sub.f.(OnFriendReadReceipt)
+			t.guard(ev, func() { f(t, ev.FriendNumber, ev.MessageID) })
+		}
+	case FriendRequestEvent:
+		if t.onFriendRequest != nil {
+			t.guard(ev, func() { t.onFriendRequest(t, ev.PublicKey, ev.Message) })
+		}
+		for _, sub := range t.subscribers.get(hookFriendRequest) {
+			f := sub.f.(OnFriendRequest)
+			t.guard(ev, func() { f(t, ev.PublicKey, ev.Message) })
+		}
+	case FriendMessageEvent:
+		if t.onFriendMessage != nil {
+			t.guard(ev, func() { t.onFriendMessage(t, ev.FriendNumber, ev.MessageType, ev.Message) })
+		}
+		for _, sub := range t.subscribers.get(hookFriendMessage) {
+			f := sub.f.(OnFriendMessage)
+			t.guard(ev, func() { f(t, ev.FriendNumber, ev.MessageType, ev.Message) })
+		}
+	case FileRecvControlEvent:
+		if t.onFileRecvControl != nil {
+			t.guard(ev, func() { t.onFileRecvControl(t, ev.FriendNumber, ev.FileNumber, ev.Control) })
+		}
+		for _, sub := range t.subscribers.get(hookFileRecvControl) {
+			f := sub.f.(OnFileRecvControl)
+			t.guard(ev, func() { f(t, ev.FriendNumber, ev.FileNumber, ev.Control) })
+		}
+	case FileChunkRequestEvent:
+		if t.onFileChunkRequest != nil {
+			t.guard(ev, func() { t.onFileChunkRequest(t, ev.FriendNumber, ev.FileNumber, ev.Position, ev.Length) })
+		}
+		for _, sub := range t.subscribers.get(hookFileChunkRequest) {
+			f := sub.f.(OnFileChunkRequest)
+			t.guard(ev, func() { f(t, ev.FriendNumber, ev.FileNumber, ev.Position, ev.Length) })
+		}
+	case FileRecvEvent:
+		if t.onFileRecv != nil {
+			t.guard(ev, func() { t.onFileRecv(t, ev.FriendNumber, ev.FileNumber, ev.Kind, ev.FileSize, ev.Filename) })
+		}
+		for _, sub := range t.subscribers.get(hookFileRecv) {
+			f := sub.f.(OnFileRecv)
+			t.guard(ev, func() { f(t, ev.FriendNumber, ev.FileNumber, ev.Kind, ev.FileSize, ev.Filename) })
+		}
+	case FileRecvChunkEvent:
+		if t.onFileRecvChunk != nil {
+			t.guard(ev, func() { t.onFileRecvChunk(t, ev.FriendNumber, ev.FileNumber, ev.Position, ev.Data) })
+		}
+		for _, sub := range t.subscribers.get(hookFileRecvChunk) {
+			f := sub.f.(OnFileRecvChunk)
+			t.guard(ev, func() { f(t, ev.FriendNumber, ev.FileNumber, ev.Position, ev.Data) })
+		}
+	case FriendLossyPacketEvent:
+		if t.onFriendLossyPacket != nil {
+			t.guard(ev, func() { t.onFriendLossyPacket(t, ev.FriendNumber, ev.Data) })
+		}
+		for _, sub := range t.subscribers.get(hookFriendLossyPacket) {
+			f := sub.f.(OnFriendLossyPacket)
+			t.guard(ev, func() { f(t, ev.FriendNumber, ev.Data) })
+		}
+	case FriendLosslessPacketEvent:
+		if t.onFriendLosslessPacket != nil {
+			t.guard(ev, func() { t.onFriendLosslessPacket(t, ev.FriendNumber, ev.Data) })
+		}
+		for _, sub := range t.subscribers.get(hookFriendLosslessPacket) {
+			f := sub.f.(OnFriendLosslessPacket)
+			t.guard(ev, func() { f(t, ev.FriendNumber, ev.Data) })
+		}
+	}
+
+	t.queueEvent(ev)
+}
@@ -0,0 +1,88 @@
+package gotox
+
+import "encoding/binary"
+import "encoding/hex"
+import "strings"
+
+/* ToxID is a Tox address as returned by SelfGetAddress and passed to FriendAdd.
+ * It consists of the public key, the nospam and a two byte checksum. */
+type ToxID [TOX_ADDRESS_SIZE]byte
+
+const toxIDNospamOffset = TOX_PUBLIC_KEY_SIZE
+const toxIDChecksumOffset = TOX_PUBLIC_KEY_SIZE + 4
+
+/* NewToxID builds the address for the given public key and nospam. */
+func NewToxID(publicKey PublicKey, nospam uint32) ToxID {
+	var id ToxID
+	copy(id[:], publicKey[:])
+	binary.BigEndian.PutUint32(id[toxIDNospamOffset:], nospam)
+	checksum := id.checksum()
+	copy(id[toxIDChecksumOffset:], checksum[:])
+	return id
+}
+
+/* ParseToxID parses the hexadecimal form of a Tox address. Upper and lower
+ * case are accepted and whitespace is ignored. The checksum is verified. */
+func ParseToxID(s string) (ToxID, error) {
+	var id ToxID
+	if !decodeHex(id[:], s) {
+		return id, ErrToxIDInvalid
+	}
+	if !id.Valid() {
+		return id, ErrToxIDBadChecksum
+	}
+
+	return id, nil
+}
+
+/* checksum computes the checksum over the public key and the nospam. */
+func (id ToxID) checksum() [2]byte {
+	var checksum [2]byte
+	for i := 0; i < toxIDChecksumOffset; i++ {
+		checksum[i%2] ^= id[i]
+	}
+	return checksum
+}
+
+/* Valid reports whether the checksum of the address is correct. */
+func (id ToxID) Valid() bool {
+	checksum := id.checksum()
+	return id[toxIDChecksumOffset] == checksum[0] && id[toxIDChecksumOffset+1] == checksum[1]
+}
+
+/* PublicKey returns the public key part of the address. */
+func (id ToxID) PublicKey() PublicKey {
+	var publicKey PublicKey
+	copy(publicKey[:], id[:toxIDNospamOffset])
+	return publicKey
+}
+
+/* Nospam returns the nospam part of the address. */
+func (id ToxID) Nospam() uint32 {
+	return binary.BigEndian.Uint32(id[toxIDNospamOffset:toxIDChecksumOffset])
+}
+
+/* Bytes returns the address as a byte slice. */
+func (id ToxID) Bytes() []byte {
+	return append([]byte(nil), id[:]...)
+}
+
+/* String returns the address as 76 upper case hex characters. */
+func (id ToxID) String() string {
+	return strings.ToUpper(hex.EncodeToString(id[:]))
+}
+
+/* MarshalText implements encoding.TextMarshaler. */
+func (id ToxID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+/* UnmarshalText implements encoding.TextUnmarshaler using ParseToxID. */
+func (id *ToxID) UnmarshalText(text []byte) error {
+	parsed, err := ParseToxID(string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
@@ -0,0 +1,92 @@
+package gotox
+
+import "strings"
+import "testing"
+
+const testAddress = "F404ABAA1C99A9D37D61AB54898F56793E1DEF8BD46B1038B9D822E8460FAB6712345678C4F6"
+
+func TestParseToxID(t *testing.T) {
+	for _, s := range []string{
+		testAddress,
+		strings.ToLower(testAddress),
+		testAddress[:30] + strings.ToLower(testAddress[30:]),
+		" \t" + testAddress + "\n",
+		testAddress[:38] + " " + testAddress[38:],
+	} {
+		id, err := ParseToxID(s)
+		if err != nil {
+			t.Errorf("ParseToxID(%q): %v", s, err)
+			continue
+		}
+		if id.String() != testAddress {
+			t.Errorf("ParseToxID(%q): got %v", s, id)
+		}
+	}
+
+	id, _ := ParseToxID(testAddress)
+	if !id.Valid() {
+		t.Error("Valid: got false")
+	}
+	if id.Nospam() != 0x12345678 {
+		t.Errorf("Nospam: got %08x", id.Nospam())
+	}
+	if id.PublicKey().String() != testAddress[:2*TOX_PUBLIC_KEY_SIZE] {
+		t.Errorf("PublicKey: got %v", id.PublicKey())
+	}
+	if NewToxID(id.PublicKey(), id.Nospam()) != id {
+		t.Errorf("NewToxID: got %v", NewToxID(id.PublicKey(), id.Nospam()))
+	}
+}
+
+func TestParseToxIDInvalid(t *testing.T) {
+	for _, test := range []struct {
+		s   string
+		err error
+	}{
+		// wrong length
+		{"", ErrToxIDInvalid},
+		{testAddress[:74], ErrToxIDInvalid},
+		{testAddress[:75], ErrToxIDInvalid},
+		{testAddress + "00", ErrToxIDInvalid},
+		// not hex
+		{testAddress[:74] + "XY", ErrToxIDInvalid},
+		{"0x" + testAddress[2:], ErrToxIDInvalid},
+		// wrong checksum
+		{testAddress[:72] + "C4F7", ErrToxIDBadChecksum},
+		{"00" + testAddress[2:], ErrToxIDBadChecksum},
+	} {
+		if _, err := ParseToxID(test.s); err != test.err {
+			t.Errorf("ParseToxID(%q): got %v, want %v", test.s, err, test.err)
+		}
+	}
+}
+
+func TestToxIDText(t *testing.T) {
+	id, err := ParseToxID(testAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := id.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != testAddress {
+		t.Errorf("MarshalText: got %s", text)
+	}
+
+	var parsed ToxID
+	if err := parsed.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if parsed != id {
+		t.Errorf("UnmarshalText: got %v, want %v", parsed, id)
+	}
+
+	if err := parsed.UnmarshalText([]byte(testAddress[:72] + "0000")); err != ErrToxIDBadChecksum {
+		t.Errorf("UnmarshalText with a bad checksum: got %v", err)
+	}
+	if parsed != id {
+		t.Error("UnmarshalText changed the address on error")
+	}
+}